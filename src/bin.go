@@ -0,0 +1,77 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrInvalidInstallment = errors.New("paycell: invalid installment count")
+
+// BIN returns the first six digits of a card number.
+func BIN(number string) string {
+	digits := make([]byte, 0, 6)
+	for i := 0; i < len(number) && len(digits) < 6; i++ {
+		if number[i] >= '0' && number[i] <= '9' {
+			digits = append(digits, number[i])
+		}
+	}
+	return string(digits)
+}
+
+// AllowsInstallment reports whether the card can be charged in n
+// installments. Single payments (n <= 1) are always allowed.
+func (b *CardBin) AllowsInstallment(n int) bool {
+	if n <= 1 {
+		return true
+	}
+	for _, count := range b.Installments {
+		if int(count) == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) GetCardBinInformation(ctx context.Context, req *CardBinRequest, p Params) (res *CardBinResult, err error) {
+	ctx, span := c.trace(ctx, "GetCardBinInformation")
+	defer func() { span.end(req, res, err) }()
+	res = new(CardBinResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getCardBinInformation/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("GetCardBinInformation", status, res.Header)
+}
+
+// CheckInstallment looks up the card's BIN and reports ErrInvalidInstallment
+// when p.Installment is not offered for it.
+func (c *Client) CheckInstallment(ctx context.Context, number string, p Params) (*CardBin, error) {
+	res, err := c.GetCardBinInformation(ctx, &CardBinRequest{BinValue: BIN(number)}, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Bins) == 0 {
+		return nil, &Error{Operation: "CheckInstallment", Description: "unknown BIN", Category: ErrInvalidCard}
+	}
+	bin := res.Bins[0]
+	if !bin.AllowsInstallment(p.Installment) {
+		return bin, fmt.Errorf("%w: %d for %s %s", ErrInvalidInstallment, p.Installment, bin.BankName, bin.CardType)
+	}
+	return bin, nil
+}
+
+func (api *API) GetCardBinInformation(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().GetCardBinInformation(ctx, &req.CardBin, api.params())
+	res.CardBin = *r
+	return res, err
+}
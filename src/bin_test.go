@@ -0,0 +1,72 @@
+package paycell_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestAllowsInstallment(t *testing.T) {
+	bin := &paycell.CardBin{Installments: []paycell.Int{2, 3, 6}}
+	debit := &paycell.CardBin{CardType: paycell.CardTypeDebit}
+	tests := []struct {
+		bin  *paycell.CardBin
+		n    int
+		want bool
+	}{
+		{bin, 0, true},
+		{bin, 1, true},
+		{bin, 3, true},
+		{bin, 4, false},
+		{bin, 12, false},
+		{debit, 1, true},
+		{debit, 2, false},
+	}
+	for _, tt := range tests {
+		if got := tt.bin.AllowsInstallment(tt.n); got != tt.want {
+			t.Errorf("AllowsInstallment(%d) for %v = %v, want %v", tt.n, tt.bin.Installments, got, tt.want)
+		}
+	}
+}
+
+func TestCheckInstallment(t *testing.T) {
+	const debit = "4508034508034509"
+	srv := paycelltest.NewServer("M1", "PWD", "APP", "KEY")
+	defer srv.Close()
+	srv.AddBin(paycell.CardBin{BinValue: paycell.Text(paycell.BIN(debit)), BankName: "DEBIT BANK", CardType: paycell.CardTypeDebit})
+	client := paycell.NewClient("M1", "PWD", "APP", srv.Option())
+	tests := []struct {
+		name        string
+		number      string
+		installment int
+		err         error
+	}{
+		{name: "allowed", number: "4355 0843 5508 4358", installment: 6},
+		{name: "single payment", number: "4355084355084358", installment: 1},
+		{name: "not offered", number: "4355084355084358", installment: 4, err: paycell.ErrInvalidInstallment},
+		{name: "debit single payment", number: debit, installment: 1},
+		{name: "debit installments", number: debit, installment: 3, err: paycell.ErrInvalidInstallment},
+		{name: "short number", number: "4355", installment: 3, err: paycell.ErrInvalidCard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := paycell.Params{ClientIP: "127.0.0.1", Installment: tt.installment}
+			bin, err := client.CheckInstallment(context.Background(), tt.number, params)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("CheckInstallment error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && string(bin.BinValue) != paycell.BIN(tt.number) {
+				t.Errorf("CheckInstallment BIN = %s, want %s", bin.BinValue, paycell.BIN(tt.number))
+			}
+		})
+	}
+
+	doer := &scripted{replies: []func() (*http.Response, error){reply(200, `{"responseHeader":{"responseCode":"0"},"cardBinInformations":[]}`)}}
+	if _, err := retryClient(doer).CheckInstallment(context.Background(), "9999990000000000", paycell.Params{Installment: 3}); !errors.Is(err, paycell.ErrInvalidCard) {
+		t.Errorf("CheckInstallment of an unknown BIN error = %v, want ErrInvalidCard", err)
+	}
+}
@@ -0,0 +1,36 @@
+package paycell
+
+import (
+	"context"
+	"time"
+)
+
+// WithDeadlineBudget bounds the multi-step payment operations (Auth, PreAuth,
+// Auth3Dinit, PreAuth3Dinit and their stored card variants) to total, of
+// which at most token is spent on card tokenization; the provision step gets
+// whatever remains. A zero duration leaves that limit unset, and an earlier
+// deadline on the caller's context always wins.
+func WithDeadlineBudget(total, token time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = total
+		c.tokenTimeout = token
+	}
+}
+
+func (c *Client) budget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// tokenize runs the CardToken step of a multi-step operation within its
+// share of the budget.
+func (c *Client) tokenize(ctx context.Context, req *CardTokenRequest) (*CardTokenResult, error) {
+	if c.tokenTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.tokenTimeout)
+		defer cancel()
+	}
+	return c.CardToken(ctx, req)
+}
@@ -0,0 +1,137 @@
+package paycell
+
+import (
+	"context"
+	"strconv"
+)
+
+func (c *Client) GetCards(ctx context.Context, req *CardsRequest, p Params) (res *CardsResult, err error) {
+	ctx, span := c.trace(ctx, "GetCards")
+	defer func() { span.end(req, res, err) }()
+	res = new(CardsResult)
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getCards/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("GetCards", status, res.Header)
+}
+
+// RegisterCard saves a card to the customer's account. The card is tokenized
+// from req.Card unless req.CardToken is set, and the EULA id is taken from
+// GetPaymentMethods when req.EulaId is empty.
+func (c *Client) RegisterCard(ctx context.Context, req *RegisterCardRequest, p Params) (res *RegisterCardResult, err error) {
+	ctx, span := c.trace(ctx, "RegisterCard")
+	defer func() { span.end(req, res, err) }()
+	res = new(RegisterCardResult)
+	if req.CardToken == nil || req.CardToken == "" {
+		token, err := c.CardToken(ctx, req.Card)
+		if err != nil {
+			res.Header = new(ResponseHeader)
+			return res, err
+		}
+		req.CardToken = token.Token
+	}
+	if req.EulaId == nil || req.EulaId == "" {
+		methods, err := c.GetPaymentMethods(ctx, new(PaymentMethodsRequest), p)
+		if err != nil {
+			res.Header = new(ResponseHeader)
+			return res, err
+		}
+		if methods.EulaID != "" {
+			req.EulaId = methods.EulaID.String()
+		}
+	}
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.send(ctx, c.endpoint("")+"/registerCard/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("RegisterCard", status, res.Header)
+}
+
+func (c *Client) UpdateCard(ctx context.Context, req *UpdateCardRequest, p Params) (res *UpdateCardResult, err error) {
+	ctx, span := c.trace(ctx, "UpdateCard")
+	defer func() { span.end(req, res, err) }()
+	res = new(UpdateCardResult)
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.send(ctx, c.endpoint("")+"/updateCard/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("UpdateCard", status, res.Header)
+}
+
+func (c *Client) DeleteCard(ctx context.Context, req *DeleteCardRequest, p Params) (res *DeleteCardResult, err error) {
+	ctx, span := c.trace(ctx, "DeleteCard")
+	defer func() { span.end(req, res, err) }()
+	res = new(DeleteCardResult)
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.send(ctx, c.endpoint("")+"/deleteCard/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("DeleteCard", status, res.Header)
+}
+
+func (api *API) GetCards(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().GetCards(ctx, &req.Cards, api.params())
+	res.Cards = *r
+	return res, err
+}
+
+func (api *API) RegisterCard(ctx context.Context, req *Request) (res Response, err error) {
+	if req.RegisterCard.Card == nil {
+		req.RegisterCard.Card = &req.CardToken
+	}
+	if req.RegisterCard.EulaId == nil && api.EulaId != "" {
+		req.RegisterCard.EulaId = api.EulaId
+	}
+	r, err := api.client().RegisterCard(ctx, &req.RegisterCard, api.params())
+	res.RegisterCard = *r
+	return res, err
+}
+
+func (api *API) UpdateCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().UpdateCard(ctx, &req.UpdateCard, api.params())
+	res.UpdateCard = *r
+	return res, err
+}
+
+func (api *API) DeleteCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().DeleteCard(ctx, &req.DeleteCard, api.params())
+	res.DeleteCard = *r
+	return res, err
+}
@@ -0,0 +1,99 @@
+package paycell
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Client holds merchant credentials and transport configuration only. It is
+// immutable after NewClient and safe for concurrent use; per-transaction data
+// is passed to each operation through Params.
+type Client struct {
+	mode         string
+	merchant     string
+	password     string
+	name         string
+	key          string
+	prefix       string
+	httpClient   Doer
+	middleware   []Middleware
+	urls         map[string]string
+	sessions     SessionStore
+	sessionTTL   time.Duration
+	formPage     *FormPage
+	retry        *RetryPolicy
+	recovery     *Recovery
+	logger       *slog.Logger
+	tracer       Tracer
+	metrics      Metrics
+	timeout      time.Duration
+	tokenTimeout time.Duration
+}
+
+type Option func(*Client)
+
+type Params struct {
+	MSISDN      string
+	ClientIP    string
+	Amount      Money
+	Installment int
+	// PointAmount is the part of Amount paid with Turkcell points.
+	PointAmount Money
+}
+
+func NewClient(merchant, password, name string, options ...Option) *Client {
+	c := &Client{merchant: merchant, password: password, name: name}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func WithStoreKey(key string) Option {
+	return func(c *Client) {
+		c.key = key
+	}
+}
+
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = prefix
+	}
+}
+
+func WithMode(mode string) Option {
+	return func(c *Client) {
+		c.mode = mode
+	}
+}
+
+func WithHTTPClient(client Doer) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware[:len(c.middleware):len(c.middleware)], middleware...)
+	}
+}
+
+func WithEndPoints(base, token, form string) Option {
+	return func(c *Client) {
+		c.urls = map[string]string{"": base, "_TOKEN": token, "_FORM": form}
+	}
+}
+
+// environment reports the mode for metrics and spans: the configured mode,
+// "CUSTOM" when custom endpoints are used without one (simulators, proxies)
+// and "PROD" otherwise.
+func (c *Client) environment() string {
+	switch {
+	case c.mode != "":
+		return c.mode
+	case c.urls != nil:
+		return "CUSTOM"
+	}
+	return "PROD"
+}
@@ -0,0 +1,72 @@
+package paycell
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var (
+	ErrDeclined          = errors.New("paycell: declined")
+	ErrInsufficientFunds = errors.New("paycell: insufficient funds")
+	ErrInvalidCard       = errors.New("paycell: invalid card")
+	ErrSystem            = errors.New("paycell: system error")
+	ErrHashMismatch      = errors.New("paycell: hash mismatch")
+	ErrThreeDSecure      = errors.New("paycell: 3D secure authentication failed")
+	ErrNotFound          = errors.New("paycell: transaction not found")
+)
+
+// Categories maps Paycell response codes to error categories.
+// Codes missing from the map are reported as ErrDeclined.
+var Categories = map[string]error{
+	"2001": ErrInvalidCard,
+	"2002": ErrInvalidCard,
+	"2003": ErrInsufficientFunds,
+	"2004": ErrDeclined,
+	"4002": ErrNotFound,
+	"9999": ErrSystem,
+}
+
+type Error struct {
+	Operation     string
+	Code          string
+	Description   string
+	TransactionId string
+	StatusCode    int
+	Category      error
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Operation + ": " + e.Description
+	}
+	return e.Operation + ": " + e.Description + " (" + e.Code + ")"
+}
+
+func (e *Error) Unwrap() error {
+	return e.Category
+}
+
+func newError(operation string, status int, header *ResponseHeader) *Error {
+	e := &Error{Operation: operation, StatusCode: status}
+	if header != nil {
+		e.Code = header.ResponseCode
+		e.Description = header.ResponseDescription
+		e.TransactionId = header.TransactionId
+	}
+	e.Category = category(e.Code, status)
+	return e
+}
+
+func category(code string, status int) error {
+	if status >= http.StatusInternalServerError {
+		return ErrSystem
+	}
+	if _, err := strconv.Atoi(code); err != nil {
+		return ErrSystem
+	}
+	if err, ok := Categories[code]; ok {
+		return err
+	}
+	return ErrDeclined
+}
@@ -0,0 +1,7 @@
+package paycell
+
+import "time"
+
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	return p.backoff(retry)
+}
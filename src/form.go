@@ -0,0 +1,148 @@
+package paycell
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// FormPage customizes the auto-submit page of Transaction3D. Empty fields
+// fall back to DefaultFormPage. Template, when set, is executed with a
+// FormData value instead of the built-in page.
+type FormPage struct {
+	Template *template.Template
+	Lang     string
+	Title    string
+	Logo     string
+	Message  string
+	Button   string
+	NoScript string
+	// Spinner only turns the spinner on: once set by WithFormPage it stays
+	// on for every page, as false cannot be told apart from unset.
+	Spinner bool
+	// Nonce is added to the inline script and style so the page works under
+	// a Content-Security-Policy with 'nonce-...' sources.
+	Nonce string
+}
+
+type FormField struct {
+	Name  string
+	Value string
+}
+
+type FormData struct {
+	FormPage
+	Action string
+	Fields []FormField
+}
+
+var DefaultFormPage = FormPage{
+	Lang:     "tr",
+	Title:    "Paycell",
+	Message:  "Lütfen bekleyiniz...",
+	Button:   "Gönder",
+	NoScript: "Devam etmek için Gönder butonuna tıklayınız.",
+}
+
+var formTemplate = template.Must(template.New("3d").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style{{with .Nonce}} nonce="{{.}}"{{end}}>body{text-align:center;margin:10px;font-family:Arial,sans-serif;font-weight:bold}.wait{display:none}.spinner{width:32px;height:32px;margin:16px auto;border:4px solid #ddd;border-top-color:#555;border-radius:50%;animation:spin 1s linear infinite}@keyframes spin{to{transform:rotate(360deg)}}</style>
+</head>
+<body>
+{{with .Logo}}<img src="{{.}}" alt="">{{end}}
+<form action="{{.Action}}" method="post" id="payment">
+{{range .Fields}}<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{end}}<noscript><p>{{.NoScript}}</p></noscript>
+<input type="submit" value="{{.Button}}" id="button">
+</form>
+<div class="wait" id="wait">{{if .Spinner}}<div class="spinner"></div>{{end}}{{.Message}}</div>
+<script{{with .Nonce}} nonce="{{.}}"{{end}}>document.getElementById("button").style.display="none";document.getElementById("wait").style.display="block";document.getElementById("payment").submit();</script>
+</body>
+</html>
+`))
+
+func WithFormPage(page FormPage) Option {
+	return func(c *Client) {
+		c.formPage = &page
+	}
+}
+
+func (c *Client) page(page *FormPage) FormPage {
+	p := DefaultFormPage
+	if c.formPage != nil {
+		p = merge(p, *c.formPage)
+	}
+	if page != nil {
+		p = merge(p, *page)
+	}
+	return p
+}
+
+func merge(p, o FormPage) FormPage {
+	if o.Template != nil {
+		p.Template = o.Template
+	}
+	if o.Lang != "" {
+		p.Lang = o.Lang
+	}
+	if o.Title != "" {
+		p.Title = o.Title
+	}
+	if o.Logo != "" {
+		p.Logo = o.Logo
+	}
+	if o.Message != "" {
+		p.Message = o.Message
+	}
+	if o.Button != "" {
+		p.Button = o.Button
+	}
+	if o.NoScript != "" {
+		p.NoScript = o.NoScript
+	}
+	if o.Nonce != "" {
+		p.Nonce = o.Nonce
+	}
+	p.Spinner = p.Spinner || o.Spinner
+	return p
+}
+
+// Render3D returns the auto-submit page posting req to the 3D form endpoint.
+func (c *Client) Render3D(ctx context.Context, req *ThreeDFormRequest, page *FormPage) (string, error) {
+	payload, err := QueryString(req)
+	if err != nil {
+		return "", err
+	}
+	data := FormData{FormPage: c.page(page), Action: c.endpoint("_FORM")}
+	for k := range payload {
+		data.Fields = append(data.Fields, FormField{Name: k, Value: payload.Get(k)})
+	}
+	sort.Slice(data.Fields, func(i, j int) bool { return data.Fields[i].Name < data.Fields[j].Name })
+	tmpl := data.Template
+	if tmpl == nil {
+		tmpl = formTemplate
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Serve3D writes the auto-submit page to w.
+func (c *Client) Serve3D(ctx context.Context, w http.ResponseWriter, req *ThreeDFormRequest, page *FormPage) error {
+	html, err := c.Render3D(ctx, req, page)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, err = w.Write([]byte(html))
+	return err
+}
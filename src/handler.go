@@ -0,0 +1,111 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrSessionNotFound = errors.New("paycell: 3D session not found")
+	ErrSessionClaimed  = errors.New("paycell: 3D session already completed")
+)
+
+// ThreeDHandler receives the 3D Secure callback posted to the callbackurl of
+// Transaction3D, verifies the result and finalizes the provision.
+//
+// Sessions are resolved with Lookup, or from the client's SessionStore when
+// Lookup is nil. OnSuccess and OnFailure may return a redirect target; when
+// they return "" (or are nil) SuccessURL or FailureURL is used. Without any target the
+// handler responds with a plain status code.
+//
+// With a SessionStore each session is claimed before it is completed, so a
+// repeated callback (a browser resubmit or a retried post) neither provisions
+// twice nor reaches OnFailure: it is redirected to DuplicateURL, or answered
+// with 409 Conflict. A completion that fails before the provision is sent
+// releases its claim, so the customer can retry it. Without a store, Lookup
+// must return a session only once.
+type ThreeDHandler struct {
+	Client       *Client
+	Lookup       func(ctx context.Context, sessionId string) (*PendingSession, error)
+	OnSuccess    func(r *http.Request, session *PendingSession, res *ProvisionResult) string
+	OnFailure    func(r *http.Request, session *PendingSession, err error) string
+	SuccessURL   string
+	FailureURL   string
+	DuplicateURL string
+}
+
+func (h *ThreeDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.fail(w, r, nil, err)
+		return
+	}
+	session, err := h.lookup(r.Context(), r.FormValue("threeDSessionId"))
+	if err != nil {
+		h.fail(w, r, nil, err)
+		return
+	}
+	var res *ProvisionResult
+	if session.Transaction == "PREAUTH" {
+		res, err = h.Client.CompletePreAuth3D(r.Context(), session)
+	} else {
+		res, err = h.Client.Complete3D(r.Context(), session)
+	}
+	if errors.Is(err, ErrSessionClaimed) {
+		if h.DuplicateURL == "" {
+			http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+			return
+		}
+		http.Redirect(w, r, h.DuplicateURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		h.fail(w, r, session, err)
+		return
+	}
+	target := h.SuccessURL
+	if h.OnSuccess != nil {
+		if url := h.OnSuccess(r, session, res); url != "" {
+			target = url
+		}
+	}
+	if target == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func (h *ThreeDHandler) lookup(ctx context.Context, id string) (*PendingSession, error) {
+	if id == "" {
+		return nil, ErrSessionNotFound
+	}
+	lookup := h.Lookup
+	if lookup == nil {
+		lookup = h.Client.LoadSession
+	}
+	session, err := lookup(ctx, id)
+	if err == nil && session == nil {
+		err = ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (h *ThreeDHandler) fail(w http.ResponseWriter, r *http.Request, session *PendingSession, err error) {
+	target := h.FailureURL
+	if h.OnFailure != nil {
+		if url := h.OnFailure(r, session, err); url != "" {
+			target = url
+		}
+	}
+	if target == "" {
+		http.Error(w, http.StatusText(http.StatusPaymentRequired), http.StatusPaymentRequired)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,53 @@
+package paycell
+
+// Provision statuses reported by Inquire.
+const (
+	StatusActive        = "ACTIVE"
+	StatusReversed      = "REVERSE"
+	StatusRefunded      = "REFUND"
+	StatusPartialRefund = "PARTIAL_REFUND"
+)
+
+// Provision types listed in the history of an inquired transaction.
+const (
+	ProvisionSale     = "SALE"
+	ProvisionPreAuth  = "PREAUTH"
+	ProvisionPostAuth = "POSTAUTH"
+	ProvisionRefund   = "REFUND"
+	ProvisionReverse  = "REVERSE"
+)
+
+func (r *InquireResult) records(provisionType string) []*ProvisionRecord {
+	var list []*ProvisionRecord
+	for _, record := range r.Provisions {
+		if string(record.ProvisionType) == provisionType && (record.ResponseCode == "" || record.ResponseCode == "0") {
+			list = append(list, record)
+		}
+	}
+	return list
+}
+
+func (r *InquireResult) Refunds() []*ProvisionRecord {
+	return r.records(ProvisionRefund)
+}
+
+func (r *InquireResult) Reversals() []*ProvisionRecord {
+	return r.records(ProvisionReverse)
+}
+
+// Refunded returns the total of successful refunds.
+func (r *InquireResult) Refunded() Money {
+	total := Money{Currency: r.Amount.Currency}
+	for _, record := range r.Refunds() {
+		total.Amount += record.Amount.Amount
+	}
+	return total
+}
+
+// Remaining returns the amount that can still be refunded.
+func (r *InquireResult) Remaining() Money {
+	if string(r.Status) == StatusReversed {
+		return Money{Currency: r.Amount.Currency}
+	}
+	return Money{Amount: r.Amount.Amount - r.Refunded().Amount, Currency: r.Amount.Currency}
+}
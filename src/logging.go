@@ -0,0 +1,200 @@
+package paycell
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLogger logs every Paycell call with its redacted request and response.
+// Fields are redacted according to their log struct tag:
+//
+//	log:"-"       dropped (CVC, expiry, OTP, PIN)
+//	log:"pan"     masked to the first 6 and last 4 digits
+//	log:"secret"  replaced with "***" (passwords, hashes, tokens)
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// MaskPAN keeps the first 6 and last 4 digits of a card number.
+func MaskPAN(number string) string {
+	if len(number) < 10 {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
+
+var (
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	taggedTypes   sync.Map
+)
+
+// Redact returns v as a JSON-like map with sensitive fields redacted. Values
+// implementing json.Marshaler are kept as they are unless they contain log
+// tagged fields, in which case they are redacted field by field like any
+// other struct.
+func Redact(v any) any {
+	return redact(reflect.ValueOf(v))
+}
+
+func redact(v reflect.Value) any {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(marshalerType) && !tagged(v.Type()) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]any)
+		redactStruct(v, fields)
+		return fields
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = redact(v.Index(i))
+		}
+		return list
+	}
+	return v.Interface()
+}
+
+func redactStruct(v reflect.Value, fields map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			redactStruct(fv, fields)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(opts, "omitempty") && empty(fv) {
+			continue
+		}
+		switch sf.Tag.Get("log") {
+		case "-":
+			continue
+		case "secret":
+			fields[name] = "***"
+		case "pan":
+			s, _ := redact(fv).(string)
+			fields[name] = MaskPAN(s)
+		default:
+			fields[name] = redact(fv)
+		}
+	}
+}
+
+// tagged reports whether t has a log tag on any of its (nested) fields.
+func tagged(t reflect.Type) bool {
+	if cached, ok := taggedTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := hasLogTag(t, make(map[reflect.Type]bool))
+	taggedTypes.Store(t, found)
+	return found
+}
+
+func hasLogTag(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if _, ok := sf.Tag.Lookup("log"); ok {
+			return true
+		}
+		if sf.IsExported() && hasLogTag(sf.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || (v.Kind() == reflect.Interface && empty(v.Elem()))
+	case reflect.Struct:
+		return v.IsZero()
+	}
+	return v.IsZero()
+}
+
+// log writes one record per call: Info for success, Warn for Paycell errors
+// and Error for transport failures.
+func (c *Client) log(ctx context.Context, url string, in, out any, status int, elapsed time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	operation := operation(url)
+	attrs := []slog.Attr{
+		slog.String("operation", operation),
+		slog.Duration("latency", elapsed),
+		slog.Int("status", status),
+		slog.Any("request", Redact(in)),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.Any("response", Redact(out)))
+		code := ""
+		if header := responseHeader(out); header != nil {
+			code = header.ResponseCode
+		}
+		attrs = append(attrs, slog.String("code", code))
+		if code != "0" {
+			level = slog.LevelWarn
+		}
+	}
+	c.logger.LogAttrs(ctx, level, "paycell "+operation, attrs...)
+}
+
+func responseHeader(out any) *ResponseHeader {
+	v := reflect.ValueOf(out)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if f := v.FieldByName("Header"); f.IsValid() {
+		header, _ := f.Interface().(*ResponseHeader)
+		return header
+	}
+	return nil
+}
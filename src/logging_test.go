@@ -0,0 +1,125 @@
+package paycell_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	const (
+		pan      = "4355084355084358"
+		cvc      = "719"
+		password = "S3cr3t-Pwd"
+		otp      = "604219"
+	)
+	srv := paycelltest.NewServer("M1", password, "APP", "KEY")
+	defer srv.Close()
+	srv.OTPCode = otp
+	srv.AddCustomer("905305289290", &paycelltest.Customer{Limit: 100000, RemainingLimit: 100000})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := paycell.NewClient("M1", password, "APP", paycell.WithStoreKey("KEY"), srv.Option(), paycell.WithLogger(logger))
+	ctx := context.Background()
+	params := paycell.Params{MSISDN: "905305289290", ClientIP: "127.0.0.1", Amount: paycell.NewMoney(1000, "TRY")}
+	card := func() *paycell.CardTokenRequest {
+		return &paycell.CardTokenRequest{CardNumber: pan, CardMonth: "12", CardYear: "30", CardCode: cvc}
+	}
+
+	token, err := client.CardToken(ctx, card())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params); err != nil {
+		t.Fatal(err)
+	}
+	sent, err := client.SendOTP(ctx, new(paycell.OTPRequest), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ValidateOTP(ctx, &paycell.OTPRequest{Token: sent.Token.String(), OTP: otp}, params); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for name, secret := range map[string]string{
+		"PAN":        pan,
+		"password":   password,
+		"card token": token.Token,
+		"hash":       token.Hash,
+		"OTP token":  sent.Token.String(),
+	} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s %q found in log output", name, secret)
+		}
+	}
+	if !strings.Contains(out, paycell.MaskPAN(pan)) {
+		t.Errorf("masked PAN %q missing from log output", paycell.MaskPAN(pan))
+	}
+	records := 0
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log record %q: %v", line, err)
+		}
+		records++
+		checkRedacted(t, "", record)
+	}
+	if records != 5 {
+		t.Errorf("got %d log records, want 5", records)
+	}
+}
+
+// checkRedacted fails on dropped fields that are present and on secret fields
+// that are not masked.
+func checkRedacted(t *testing.T, path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch key {
+			case "cvcNo", "expireDateMonth", "expireDateYear", "otp", "pin":
+				t.Errorf("%s.%s = %v, want dropped", path, key, value)
+			case "applicationPwd", "hashData", "cardToken", "token":
+				if value != "***" {
+					t.Errorf("%s.%s = %v, want ***", path, key, value)
+				}
+			default:
+				checkRedacted(t, path+"."+key, value)
+			}
+		}
+	case []any:
+		for _, value := range v {
+			checkRedacted(t, path, value)
+		}
+	}
+}
+
+type taggedMarshaler struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret" log:"secret"`
+}
+
+func (m taggedMarshaler) MarshalJSON() ([]byte, error) {
+	type plain taggedMarshaler
+	return json.Marshal(plain(m))
+}
+
+func TestRedactMarshaler(t *testing.T) {
+	redacted, ok := paycell.Redact(taggedMarshaler{Name: "n", Secret: "s"}).(map[string]any)
+	if !ok {
+		t.Fatalf("tagged marshaler was not redacted: %#v", redacted)
+	}
+	if redacted["secret"] != "***" || redacted["name"] != "n" {
+		t.Errorf("Redact(taggedMarshaler) = %v", redacted)
+	}
+	money := paycell.NewMoney(150, "TRY")
+	if got := paycell.Redact(money); got != money {
+		t.Errorf("Redact(Money) = %#v, want the value itself", got)
+	}
+}
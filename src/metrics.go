@@ -0,0 +1,190 @@
+package paycell
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of Paycell calls. Request is invoked for
+// every HTTP call, including retries; code is the Paycell response code,
+// "error" for transport failures and "invalid" for unusable responses.
+type Metrics interface {
+	Request(operation, mode, code string, latency time.Duration)
+	HashFailure(operation string)
+	ThreeD(success bool)
+	OTPFailure()
+}
+
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Request(operation, mode, code string, latency time.Duration) {}
+func (noopMetrics) HashFailure(operation string)                                {}
+func (noopMetrics) ThreeD(success bool)                                         {}
+func (noopMetrics) OTPFailure()                                                 {}
+
+func (c *Client) stats() Metrics {
+	if c.metrics == nil {
+		return noopMetrics{}
+	}
+	return c.metrics
+}
+
+// operation returns the endpoint name of url, e.g. "provision".
+func operation(url string) string {
+	return path.Base(strings.TrimSuffix(url, "/"))
+}
+
+// DefaultBuckets are the latency histogram buckets in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// PrometheusMetrics collects Metrics in memory and serves them in the
+// Prometheus text exposition format:
+//
+//	paycell_requests_total{operation,mode,code}
+//	paycell_request_duration_seconds{operation,mode}
+//	paycell_hash_failures_total{operation}
+//	paycell_threed_total{result="success"|"failure"}
+//	paycell_otp_failures_total
+type PrometheusMetrics struct {
+	mu       sync.Mutex
+	buckets  []float64
+	requests map[[3]string]uint64
+	latency  map[[2]string]*histogram
+	hash     map[string]uint64
+	threeD   map[string]uint64
+	otp      uint64
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusMetrics uses DefaultBuckets unless buckets are given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &PrometheusMetrics{
+		buckets:  append([]float64(nil), buckets...),
+		requests: make(map[[3]string]uint64),
+		latency:  make(map[[2]string]*histogram),
+		hash:     make(map[string]uint64),
+		threeD:   make(map[string]uint64),
+	}
+}
+
+func (m *PrometheusMetrics) Request(operation, mode, code string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[3]string{operation, mode, code}]++
+	key := [2]string{operation, mode}
+	h, ok := m.latency[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[key] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (m *PrometheusMetrics) HashFailure(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hash[operation]++
+}
+
+func (m *PrometheusMetrics) ThreeD(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if success {
+		m.threeD["success"]++
+	} else {
+		m.threeD["failure"]++
+	}
+}
+
+func (m *PrometheusMetrics) OTPFailure() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.otp++
+}
+
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the collected metrics in the Prometheus text format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("# HELP paycell_requests_total Paycell calls by operation, mode and response code.\n")
+	b.WriteString("# TYPE paycell_requests_total counter\n")
+	for _, key := range sorted(m.requests) {
+		fmt.Fprintf(&b, "paycell_requests_total{operation=%s,mode=%s,code=%s} %d\n", quote(key[0]), quote(key[1]), quote(key[2]), m.requests[key])
+	}
+	b.WriteString("# HELP paycell_request_duration_seconds Paycell call latency.\n")
+	b.WriteString("# TYPE paycell_request_duration_seconds histogram\n")
+	for _, key := range sorted(m.latency) {
+		h := m.latency[key]
+		labels := "operation=" + quote(key[0]) + ",mode=" + quote(key[1])
+		for i, bound := range m.buckets {
+			fmt.Fprintf(&b, "paycell_request_duration_seconds_bucket{%s,le=%s} %d\n", labels, quote(strconv.FormatFloat(bound, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(&b, "paycell_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "paycell_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "paycell_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	b.WriteString("# HELP paycell_hash_failures_total Responses whose hash did not verify.\n")
+	b.WriteString("# TYPE paycell_hash_failures_total counter\n")
+	for _, key := range sorted(m.hash) {
+		fmt.Fprintf(&b, "paycell_hash_failures_total{operation=%s} %d\n", quote(key), m.hash[key])
+	}
+	b.WriteString("# HELP paycell_threed_total 3D Secure authentications by result.\n")
+	b.WriteString("# TYPE paycell_threed_total counter\n")
+	for _, key := range sorted(m.threeD) {
+		fmt.Fprintf(&b, "paycell_threed_total{result=%s} %d\n", quote(key), m.threeD[key])
+	}
+	b.WriteString("# HELP paycell_otp_failures_total Rejected OTP validations.\n")
+	b.WriteString("# TYPE paycell_otp_failures_total counter\n")
+	fmt.Fprintf(&b, "paycell_otp_failures_total %d\n", m.otp)
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func sorted[K [3]string | [2]string | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,73 @@
+package paycell_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := paycell.NewPrometheusMetrics(0.1, 1)
+	m.Request("provision", "TEST", "0", 62500*time.Microsecond)
+	m.Request("provision", "TEST", "0", 500*time.Millisecond)
+	m.Request("provision", "TEST", "9999", 2*time.Second)
+	m.Request("a\"b\\c\nd", "TEST", "error", 0)
+	m.HashFailure("getCardTokenSecure")
+	m.ThreeD(true)
+	m.ThreeD(false)
+	m.ThreeD(true)
+	m.OTPFailure()
+	m.OTPFailure()
+	want := `# HELP paycell_requests_total Paycell calls by operation, mode and response code.
+# TYPE paycell_requests_total counter
+paycell_requests_total{operation="a\"b\\c\nd",mode="TEST",code="error"} 1
+paycell_requests_total{operation="provision",mode="TEST",code="0"} 2
+paycell_requests_total{operation="provision",mode="TEST",code="9999"} 1
+# HELP paycell_request_duration_seconds Paycell call latency.
+# TYPE paycell_request_duration_seconds histogram
+paycell_request_duration_seconds_bucket{operation="a\"b\\c\nd",mode="TEST",le="0.1"} 1
+paycell_request_duration_seconds_bucket{operation="a\"b\\c\nd",mode="TEST",le="1"} 1
+paycell_request_duration_seconds_bucket{operation="a\"b\\c\nd",mode="TEST",le="+Inf"} 1
+paycell_request_duration_seconds_sum{operation="a\"b\\c\nd",mode="TEST"} 0
+paycell_request_duration_seconds_count{operation="a\"b\\c\nd",mode="TEST"} 1
+paycell_request_duration_seconds_bucket{operation="provision",mode="TEST",le="0.1"} 1
+paycell_request_duration_seconds_bucket{operation="provision",mode="TEST",le="1"} 2
+paycell_request_duration_seconds_bucket{operation="provision",mode="TEST",le="+Inf"} 3
+paycell_request_duration_seconds_sum{operation="provision",mode="TEST"} 2.5625
+paycell_request_duration_seconds_count{operation="provision",mode="TEST"} 3
+# HELP paycell_hash_failures_total Responses whose hash did not verify.
+# TYPE paycell_hash_failures_total counter
+paycell_hash_failures_total{operation="getCardTokenSecure"} 1
+# HELP paycell_threed_total 3D Secure authentications by result.
+# TYPE paycell_threed_total counter
+paycell_threed_total{result="failure"} 1
+paycell_threed_total{result="success"} 2
+# HELP paycell_otp_failures_total Rejected OTP validations.
+# TYPE paycell_otp_failures_total counter
+paycell_otp_failures_total 2
+`
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteTo =\n%s\nwant\n%s", got, want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("WriteTo wrote %d bytes, reported %d", len(want), n)
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("ServeHTTP = %d\n%s", w.Code, w.Body)
+	}
+}
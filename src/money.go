@@ -0,0 +1,153 @@
+package paycell
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount   = errors.New("paycell: invalid amount")
+	ErrInvalidCurrency = errors.New("paycell: invalid currency")
+)
+
+// Currencies maps supported ISO 4217 codes to their number of minor unit digits.
+var Currencies = map[string]int{
+	"TRY": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+}
+
+// Money is an amount in minor units (kuruş for TRY) and an ISO 4217 currency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney parses a decimal string such as "10", "1.5" or "1,50".
+func ParseMoney(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	digits, ok := Currencies[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if strings.Count(s, ".")+strings.Count(s, ",") > 1 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	whole, fraction, _ := strings.Cut(strings.ReplaceAll(s, ",", "."), ".")
+	if whole == "" || len(fraction) > digits || !numeric(whole) || !numeric(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", digits-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func MoneyFromFloat(amount float64, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	digits, ok := Currencies[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	minor := math.Round(amount * math.Pow10(digits))
+	if math.IsNaN(minor) || math.IsInf(minor, 0) || math.Abs(minor) > math.MaxInt64/2 {
+		return Money{}, fmt.Errorf("%w: %v", ErrInvalidAmount, amount)
+	}
+	return Money{Amount: int64(minor), Currency: currency}, nil
+}
+
+func numeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units as sent to Paycell.
+func (m Money) Minor() string {
+	return strconv.FormatInt(m.Amount, 10)
+}
+
+// Decimal formats the amount with the currency's minor unit digits, e.g. "1.50".
+func (m Money) Decimal() string {
+	digits := Currencies[m.Currency]
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if digits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+	s := fmt.Sprintf("%0*d", digits+1, amount)
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrInvalidCurrency, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrInvalidCurrency, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Cmp compares m and o like Add and Sub, panicking when their currencies
+// differ instead of comparing amounts in different units.
+func (m Money) Cmp(o Money) int {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("paycell: comparing %s and %s", m.Currency, o.Currency))
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Validate reports whether m can be sent as a payment amount.
+func (m Money) Validate() error {
+	if _, ok := Currencies[m.Currency]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, m.Currency)
+	}
+	if m.Amount <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, m)
+	}
+	return nil
+}
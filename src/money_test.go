@@ -0,0 +1,100 @@
+package paycell_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount string
+		minor  int64
+		err    error
+	}{
+		{"10", 1000, nil},
+		{"1.5", 150, nil},
+		{"1,50", 150, nil},
+		{"0.01", 1, nil},
+		{" 12.34 ", 1234, nil},
+		{"-1", -100, nil},
+		{"1.", 100, nil},
+		{"1.505", 0, paycell.ErrInvalidAmount},
+		{"", 0, paycell.ErrInvalidAmount},
+		{"-", 0, paycell.ErrInvalidAmount},
+		{".5", 0, paycell.ErrInvalidAmount},
+		{"1.2.3", 0, paycell.ErrInvalidAmount},
+		{"1,000.50", 0, paycell.ErrInvalidAmount},
+		{"1e3", 0, paycell.ErrInvalidAmount},
+		{"abc", 0, paycell.ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		m, err := paycell.ParseMoney(tt.amount, "try")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseMoney(%q) error = %v, want %v", tt.amount, err, tt.err)
+			continue
+		}
+		if err == nil && (m.Amount != tt.minor || m.Currency != "TRY") {
+			t.Errorf("ParseMoney(%q) = %+v, want %d TRY", tt.amount, m, tt.minor)
+		}
+	}
+	if _, err := paycell.ParseMoney("10", "XYZ"); !errors.Is(err, paycell.ErrInvalidCurrency) {
+		t.Errorf("ParseMoney with unknown currency error = %v, want ErrInvalidCurrency", err)
+	}
+}
+
+func TestMoneyDecimalMinor(t *testing.T) {
+	tests := []struct {
+		minor   int64
+		decimal string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{150, "1.50"},
+		{1000, "10.00"},
+		{123456, "1234.56"},
+		{-5, "-0.05"},
+		{-100, "-1.00"},
+	}
+	for _, tt := range tests {
+		m := paycell.NewMoney(tt.minor, "TRY")
+		if got := m.Decimal(); got != tt.decimal {
+			t.Errorf("NewMoney(%d).Decimal() = %q, want %q", tt.minor, got, tt.decimal)
+		}
+		parsed, err := paycell.ParseMoney(m.Decimal(), m.Currency)
+		if err != nil || parsed != m {
+			t.Errorf("ParseMoney(%q) = %+v, %v, want %+v", m.Decimal(), parsed, err, m)
+		}
+		if minor, err := strconv.ParseInt(m.Minor(), 10, 64); err != nil || minor != tt.minor {
+			t.Errorf("NewMoney(%d).Minor() = %q", tt.minor, m.Minor())
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := paycell.NewMoney(1000, "TRY"), paycell.NewMoney(250, "TRY")
+	if sum, err := a.Add(b); err != nil || sum != paycell.NewMoney(1250, "TRY") {
+		t.Errorf("Add = %v, %v", sum, err)
+	}
+	if diff, err := a.Sub(b); err != nil || diff != paycell.NewMoney(750, "TRY") {
+		t.Errorf("Sub = %v, %v", diff, err)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Errorf("Cmp = %d, %d, %d", a.Cmp(b), b.Cmp(a), a.Cmp(a))
+	}
+	usd := paycell.NewMoney(250, "USD")
+	if _, err := a.Add(usd); !errors.Is(err, paycell.ErrInvalidCurrency) {
+		t.Errorf("Add in another currency error = %v, want ErrInvalidCurrency", err)
+	}
+	if _, err := a.Sub(usd); !errors.Is(err, paycell.ErrInvalidCurrency) {
+		t.Errorf("Sub in another currency error = %v, want ErrInvalidCurrency", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Cmp in another currency did not panic")
+		}
+	}()
+	a.Cmp(usd)
+}
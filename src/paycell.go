@@ -8,10 +8,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,200 +28,435 @@ var EndPoints = map[string]string{
 
 type any = interface{}
 
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var DefaultClient Doer = &http.Client{Timeout: 30 * time.Second}
+
 type API struct {
-	Mode     string
-	Merchant string
-	Password string
-	Name     string
-	Key      string
-	EulaId   string
-	Prefix   string
-	ISDN     string
-	IPv4     string
-	Amount   string
+	HTTPClient   Doer
+	Middleware   []Middleware
+	URLs         map[string]string
+	Sessions     SessionStore
+	SessionTTL   time.Duration
+	FormPage     *FormPage
+	Retry        *RetryPolicy
+	Recovery     *Recovery
+	Logger       *slog.Logger
+	Tracer       Tracer
+	Metrics      Metrics
+	Timeout      time.Duration
+	TokenTimeout time.Duration
+	Mode         string
+	Merchant     string
+	Password     string
+	Name         string
+	Key          string
+	EulaId       string
+	Prefix       string
+	ISDN         string
+	IPv4         string
+	Money        Money
+	Points       Money
+	// Amount is the amount in minor units, read only when Money is unset.
+	//
+	// Deprecated: Use Money or SetAmount.
+	Amount string
+	// Currency is the currency of Amount.
+	//
+	// Deprecated: Use Money or SetAmount.
 	Currency string
 }
 
 type (
 	Request struct {
-		CardToken struct {
-			Header     RequestHeader `json:"header,omitempty"`
-			CardNumber any           `json:"creditCardNo,omitempty"`
-			CardMonth  any           `json:"expireDateMonth,omitempty"`
-			CardYear   any           `json:"expireDateYear,omitempty"`
-			CardCode   any           `json:"cvcNo,omitempty"`
-			Hash       any           `json:"hashData,omitempty"`
-		}
-		Provision struct {
-			Header        RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn        any           `json:"msisdn,omitempty"`
-			MerchantCode  any           `json:"merchantCode,omitempty"`
-			CardId        any           `json:"cardId,omitempty"`
-			CardToken     any           `json:"cardToken,omitempty"`
-			RefNo         any           `json:"referenceNumber,omitempty"`
-			OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
-			Amount        any           `json:"amount,omitempty"`
-			PointAmount   any           `json:"pointAmount,omitempty"`
-			Currency      any           `json:"currency,omitempty"`
-			Installment   any           `json:"installmentCount,omitempty"`
-			PaymentType   any           `json:"paymentType,omitempty"`
-			AcquirerBank  any           `json:"acquirerBankCode,omitempty"`
-			ThreeDSession any           `json:"threeDSessionId,omitempty"`
-			Pin           any           `json:"pin,omitempty"`
-		}
-		Refund struct {
-			Header        RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn        any           `json:"msisdn,omitempty"`
-			MerchantCode  any           `json:"merchantCode,omitempty"`
-			Amount        any           `json:"amount,omitempty"`
-			Currency      any           `json:"currency,omitempty"`
-			RefNo         any           `json:"referenceNumber,omitempty"`
-			OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
-		}
-		Cancel struct {
-			Header        RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn        any           `json:"msisdn,omitempty"`
-			MerchantCode  any           `json:"merchantCode,omitempty"`
-			RefNo         any           `json:"referenceNumber,omitempty"`
-			OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
-		}
-		ThreeDSession struct {
-			Header       RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn       any           `json:"msisdn,omitempty"`
-			MerchantCode any           `json:"merchantCode,omitempty"`
-			CardId       any           `json:"cardId,omitempty"`
-			CardToken    any           `json:"cardToken,omitempty"`
-			RefNo        any           `json:"referenceNumber,omitempty"`
-			Amount       any           `json:"amount,omitempty"`
-			PointAmount  any           `json:"pointAmount,omitempty"`
-			Currency     any           `json:"currency,omitempty"`
-			Installment  any           `json:"installmentCount,omitempty"`
-			Target       any           `json:"target,omitempty"`
-			Transaction  any           `json:"transactionType,omitempty"`
-		}
-		ThreeDResult struct {
-			Header        RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn        any           `json:"msisdn,omitempty"`
-			MerchantCode  any           `json:"merchantCode,omitempty"`
-			RefNo         any           `json:"referenceNumber,omitempty"`
-			ThreeDSession any           `json:"threeDSessionId,omitempty"`
-		}
-		ThreeDForm struct {
-			ThreeDSession  any `form:"threeDSessionId,omitempty"`
-			CallbackUrl    any `form:"callbackurl,omitempty"`
-			IsPoint        any `form:"isPoint,omitempty"`
-			IsPost3DResult any `form:"isPost3DResult,omitempty"`
-		}
-		PaymentMethods struct {
-			Header RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn any           `json:"msisdn,omitempty"`
-		}
-		MobilePayment struct {
-			Header RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn any           `json:"msisdn,omitempty"`
-			EulaID any           `json:"eulaID,omitempty"`
-		}
-		OTP struct {
-			Header   RequestHeader `json:"requestHeader,omitempty"`
-			MSisdn   any           `json:"msisdn,omitempty"`
-			Amount   any           `json:"amount,omitempty"`
-			Currency any           `json:"currency,omitempty"`
-			RefNo    any           `json:"referenceNumber,omitempty"`
-			OTP      any           `json:"otp,omitempty"`
-			Token    any           `json:"token,omitempty"`
-		}
+		CardToken      CardTokenRequest
+		Provision      ProvisionRequest
+		Refund         RefundRequest
+		Cancel         CancelRequest
+		Inquire        InquireRequest
+		ThreeDSession  ThreeDSessionRequest
+		ThreeDResult   ThreeDResultRequest
+		ThreeDForm     ThreeDFormRequest
+		PaymentMethods PaymentMethodsRequest
+		CardBin        CardBinRequest
+		PointBalance   PointBalanceRequest
+		Reconciliation SummaryReconciliationRequest
+		History        ProvisionHistoryRequest
+		Cards          CardsRequest
+		RegisterCard   RegisterCardRequest
+		UpdateCard     UpdateCardRequest
+		DeleteCard     DeleteCardRequest
+		MobilePayment  MobilePaymentRequest
+		OTP            OTPRequest
+	}
+	CardTokenRequest struct {
+		Header     RequestHeader `json:"header,omitempty"`
+		CardNumber any           `json:"creditCardNo,omitempty" log:"pan"`
+		CardMonth  any           `json:"expireDateMonth,omitempty" log:"-"`
+		CardYear   any           `json:"expireDateYear,omitempty" log:"-"`
+		CardCode   any           `json:"cvcNo,omitempty" log:"-"`
+		Hash       any           `json:"hashData,omitempty" log:"secret"`
+	}
+	ProvisionRequest struct {
+		Header        RequestHeader     `json:"requestHeader,omitempty"`
+		MSisdn        any               `json:"msisdn,omitempty"`
+		MerchantCode  any               `json:"merchantCode,omitempty"`
+		CardId        any               `json:"cardId,omitempty"`
+		CardToken     any               `json:"cardToken,omitempty" log:"secret"`
+		RefNo         any               `json:"referenceNumber,omitempty"`
+		OriginalRefNo any               `json:"originalReferenceNumber,omitempty"`
+		Amount        any               `json:"amount,omitempty"`
+		PointAmount   any               `json:"pointAmount,omitempty"`
+		Currency      any               `json:"currency,omitempty"`
+		Installment   any               `json:"installmentCount,omitempty"`
+		PaymentType   any               `json:"paymentType,omitempty"`
+		AcquirerBank  any               `json:"acquirerBankCode,omitempty"`
+		ThreeDSession any               `json:"threeDSessionId,omitempty"`
+		Pin           any               `json:"pin,omitempty" log:"-"`
+		Card          *CardTokenRequest `json:"-"`
+	}
+	RefundRequest struct {
+		Header        RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn        any           `json:"msisdn,omitempty"`
+		MerchantCode  any           `json:"merchantCode,omitempty"`
+		Amount        any           `json:"amount,omitempty"`
+		PointAmount   any           `json:"pointAmount,omitempty"`
+		Currency      any           `json:"currency,omitempty"`
+		RefNo         any           `json:"referenceNumber,omitempty"`
+		OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
+	}
+	CancelRequest struct {
+		Header        RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn        any           `json:"msisdn,omitempty"`
+		MerchantCode  any           `json:"merchantCode,omitempty"`
+		RefNo         any           `json:"referenceNumber,omitempty"`
+		OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
+	}
+	InquireRequest struct {
+		Header        RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn        any           `json:"msisdn,omitempty"`
+		MerchantCode  any           `json:"merchantCode,omitempty"`
+		RefNo         any           `json:"referenceNumber,omitempty"`
+		OriginalRefNo any           `json:"originalReferenceNumber,omitempty"`
+	}
+	ThreeDSessionRequest struct {
+		Header       RequestHeader     `json:"requestHeader,omitempty"`
+		MSisdn       any               `json:"msisdn,omitempty"`
+		MerchantCode any               `json:"merchantCode,omitempty"`
+		CardId       any               `json:"cardId,omitempty"`
+		CardToken    any               `json:"cardToken,omitempty" log:"secret"`
+		RefNo        any               `json:"referenceNumber,omitempty"`
+		Amount       any               `json:"amount,omitempty"`
+		PointAmount  any               `json:"pointAmount,omitempty"`
+		Currency     any               `json:"currency,omitempty"`
+		Installment  any               `json:"installmentCount,omitempty"`
+		Target       any               `json:"target,omitempty"`
+		Transaction  any               `json:"transactionType,omitempty"`
+		Card         *CardTokenRequest `json:"-"`
+	}
+	ThreeDResultRequest struct {
+		Header        RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn        any           `json:"msisdn,omitempty"`
+		MerchantCode  any           `json:"merchantCode,omitempty"`
+		RefNo         any           `json:"referenceNumber,omitempty"`
+		ThreeDSession any           `json:"threeDSessionId,omitempty"`
+	}
+	ThreeDFormRequest struct {
+		ThreeDSession  any `form:"threeDSessionId,omitempty"`
+		CallbackUrl    any `form:"callbackurl,omitempty"`
+		IsPoint        any `form:"isPoint,omitempty"`
+		IsPost3DResult any `form:"isPost3DResult,omitempty"`
+	}
+	PaymentMethodsRequest struct {
+		Header RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn any           `json:"msisdn,omitempty"`
+	}
+	SummaryReconciliationRequest struct {
+		Header              RequestHeader `json:"requestHeader,omitempty"`
+		MerchantCode        any           `json:"merchantCode,omitempty"`
+		ReconciliationDate  any           `json:"reconciliationDate,omitempty"`
+		TotalSaleAmount     any           `json:"totalSaleAmount,omitempty"`
+		TotalSaleCount      any           `json:"totalSaleCount,omitempty"`
+		TotalRefundAmount   any           `json:"totalRefundAmount,omitempty"`
+		TotalRefundCount    any           `json:"totalRefundCount,omitempty"`
+		TotalReverseAmount  any           `json:"totalReverseAmount,omitempty"`
+		TotalReverseCount   any           `json:"totalReverseCount,omitempty"`
+		TotalPreAuthAmount  any           `json:"totalPreAuthAmount,omitempty"`
+		TotalPreAuthCount   any           `json:"totalPreAuthCount,omitempty"`
+		TotalPostAuthAmount any           `json:"totalPostAuthAmount,omitempty"`
+		TotalPostAuthCount  any           `json:"totalPostAuthCount,omitempty"`
+	}
+	ProvisionHistoryRequest struct {
+		Header             RequestHeader `json:"requestHeader,omitempty"`
+		MerchantCode       any           `json:"merchantCode,omitempty"`
+		ReconciliationDate any           `json:"reconciliationDate,omitempty"`
+		Index              any           `json:"index,omitempty"`
+		PageSize           any           `json:"pageSize,omitempty"`
+	}
+	PointBalanceRequest struct {
+		Header RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn any           `json:"msisdn,omitempty"`
+	}
+	CardBinRequest struct {
+		Header   RequestHeader `json:"requestHeader,omitempty"`
+		BinValue any           `json:"binValue,omitempty"`
+	}
+	CardsRequest struct {
+		Header RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn any           `json:"msisdn,omitempty"`
+	}
+	RegisterCardRequest struct {
+		Header        RequestHeader     `json:"requestHeader,omitempty"`
+		MSisdn        any               `json:"msisdn,omitempty"`
+		CardToken     any               `json:"cardToken,omitempty" log:"secret"`
+		Alias         any               `json:"alias,omitempty"`
+		EulaId        any               `json:"eulaId,omitempty"`
+		IsDefault     any               `json:"isDefault,omitempty"`
+		ThreeDSession any               `json:"threeDSessionId,omitempty"`
+		Card          *CardTokenRequest `json:"-"`
+	}
+	UpdateCardRequest struct {
+		Header        RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn        any           `json:"msisdn,omitempty"`
+		CardId        any           `json:"cardId,omitempty"`
+		Alias         any           `json:"alias,omitempty"`
+		EulaId        any           `json:"eulaId,omitempty"`
+		IsDefault     any           `json:"isDefault,omitempty"`
+		ThreeDSession any           `json:"threeDSessionId,omitempty"`
+	}
+	DeleteCardRequest struct {
+		Header RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn any           `json:"msisdn,omitempty"`
+		CardId any           `json:"cardId,omitempty"`
+	}
+	MobilePaymentRequest struct {
+		Header RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn any           `json:"msisdn,omitempty"`
+		EulaID any           `json:"eulaID,omitempty"`
+	}
+	OTPRequest struct {
+		Header   RequestHeader `json:"requestHeader,omitempty"`
+		MSisdn   any           `json:"msisdn,omitempty"`
+		Amount   any           `json:"amount,omitempty"`
+		Currency any           `json:"currency,omitempty"`
+		RefNo    any           `json:"referenceNumber,omitempty"`
+		OTP      any           `json:"otp,omitempty" log:"-"`
+		Token    any           `json:"token,omitempty" log:"secret"`
 	}
 )
 
 type (
 	Response struct {
-		CardToken struct {
-			Header *ResponseHeader `json:"header,omitempty"`
-			Token  string          `json:"cardToken,omitempty"`
-			Hash   string          `json:"hashData,omitempty"`
-		}
-		Provision struct {
-			Header       *ResponseHeader `json:"responseHeader,omitempty"`
-			OrderId      any             `json:"orderId,omitempty"`
-			RefNo        any             `json:"referenceNumber,omitempty"`
-			OrderDate    any             `json:"reconciliationDate,omitempty"`
-			ApprovalCode any             `json:"approvalCode,omitempty"`
-			AcquirerBank any             `json:"acquirerBankCode,omitempty"`
-			IssuerBank   any             `json:"issuerBankCode,omitempty"`
-		}
-		Refund struct {
-			Header       *ResponseHeader `json:"responseHeader,omitempty"`
-			OrderId      any             `json:"orderId,omitempty"`
-			OrderDate    any             `json:"reconciliationDate,omitempty"`
-			ApprovalCode any             `json:"approvalCode,omitempty"`
-			StatusCode   any             `json:"retryStatusCode,omitempty"`
-			Description  any             `json:"retryStatusDescription,omitempty"`
-		}
-		Cancel struct {
-			Header       *ResponseHeader `json:"responseHeader,omitempty"`
-			OrderId      any             `json:"orderId,omitempty"`
-			OrderDate    any             `json:"reconciliationDate,omitempty"`
-			ApprovalCode any             `json:"approvalCode,omitempty"`
-			StatusCode   any             `json:"retryStatusCode,omitempty"`
-			Description  any             `json:"retryStatusDescription,omitempty"`
-		}
-		ThreeDSession struct {
-			Header        *ResponseHeader `json:"responseHeader,omitempty"`
-			ThreeDSession any             `json:"threeDSessionId,omitempty"`
-		}
-		ThreeDResult struct {
-			Header         *ResponseHeader `json:"responseHeader,omitempty"`
-			CurrentStep    any             `json:"currentStep,omitempty"`
-			MdErrorMessage any             `json:"mdErrorMessage,omitempty"`
-			MdStatus       any             `json:"mdStatus,omitempty"`
-			Operation      struct {
-				Result      string `json:"threeDResult,omitempty"`
-				Description string `json:"threeDResultDescription,omitempty"`
-			} `json:"threeDOperationResult,omitempty"`
-		}
-		PaymentMethods struct {
-			Header   *ResponseHeader `json:"responseHeader,omitempty"`
-			EulaID   any             `json:"eulaID,omitempty"`
-			CardList []*struct {
-				CardBrand         any  `json:"cardBrand,omitempty"`
-				CardId            any  `json:"cardId,omitempty"`
-				CardType          any  `json:"cardType,omitempty"`
-				MaskedCardNo      any  `json:"maskedCardNo,omitempty"`
-				Alias             any  `json:"alias,omitempty"`
-				ActivationDate    any  `json:"activationDate,omitempty"`
-				IsDefault         bool `json:"isDefault,omitempty"`
-				IsExpired         bool `json:"isExpired,omitempty"`
-				ShowEulaId        bool `json:"showEulaId,omitempty"`
-				IsThreeDValidated bool `json:"isThreeDValidated,omitempty"`
-				IsOTPValidated    bool `json:"isOTPValidated,omitempty"`
-			} `json:"cardList,omitempty"`
-			MobilePayment *struct {
-				EulaId         any  `json:"eulaId,omitempty"`
-				EulaUrl        any  `json:"eulaUrl,omitempty"`
-				SignedEulaId   any  `json:"signedEulaId,omitempty"`
-				StatementDate  any  `json:"statementDate,omitempty"`
-				Limit          any  `json:"limit,omitempty"`
-				MaxLimit       any  `json:"maxLimit,omitempty"`
-				RemainingLimit any  `json:"remainingLimit,omitempty"`
-				IsDcbOpen      bool `json:"isDcbOpen,omitempty"`
-				IsEulaExpired  bool `json:"isEulaExpired,omitempty"`
-			} `json:"mobilePayment,omitempty"`
-		}
-		MobilePayment struct {
-			Header *ResponseHeader `json:"responseHeader,omitempty"`
-		}
-		OTP struct {
-			Header     *ResponseHeader `json:"responseHeader,omitempty"`
-			Token      any             `json:"token,omitempty"`
-			ExpireDate any             `json:"expireDate,omitempty"`
-			RetryCount any             `json:"remainingRetryCount,omitempty"`
-		}
+		CardToken      CardTokenResult
+		Provision      ProvisionResult
+		Refund         RefundResult
+		Cancel         CancelResult
+		Inquire        InquireResult
+		ThreeDSession  ThreeDSessionResult
+		ThreeDResult   ThreeDResult
+		PaymentMethods PaymentMethods
+		CardBin        CardBinResult
+		PointBalance   PointBalanceResult
+		Reconciliation SummaryReconciliationResult
+		History        ProvisionHistoryResult
+		Cards          CardsResult
+		RegisterCard   RegisterCardResult
+		UpdateCard     UpdateCardResult
+		DeleteCard     DeleteCardResult
+		MobilePayment  MobilePaymentResult
+		OTP            OTPResult
+	}
+	CardTokenResult struct {
+		Header *ResponseHeader `json:"header,omitempty"`
+		Token  string          `json:"cardToken,omitempty" log:"secret"`
+		Hash   string          `json:"hashData,omitempty" log:"secret"`
+	}
+	ProvisionResult struct {
+		Header       *ResponseHeader `json:"responseHeader,omitempty"`
+		OrderId      Text            `json:"orderId,omitempty"`
+		RefNo        Text            `json:"referenceNumber,omitempty"`
+		OrderDate    Date            `json:"reconciliationDate,omitempty"`
+		ApprovalCode Text            `json:"approvalCode,omitempty"`
+		AcquirerBank Text            `json:"acquirerBankCode,omitempty"`
+		IssuerBank   Text            `json:"issuerBankCode,omitempty"`
+	}
+	RefundResult struct {
+		Header       *ResponseHeader `json:"responseHeader,omitempty"`
+		OrderId      Text            `json:"orderId,omitempty"`
+		OrderDate    Date            `json:"reconciliationDate,omitempty"`
+		ApprovalCode Text            `json:"approvalCode,omitempty"`
+		StatusCode   Text            `json:"retryStatusCode,omitempty"`
+		Description  Text            `json:"retryStatusDescription,omitempty"`
+	}
+	CancelResult struct {
+		Header       *ResponseHeader `json:"responseHeader,omitempty"`
+		OrderId      Text            `json:"orderId,omitempty"`
+		OrderDate    Date            `json:"reconciliationDate,omitempty"`
+		ApprovalCode Text            `json:"approvalCode,omitempty"`
+		StatusCode   Text            `json:"retryStatusCode,omitempty"`
+		Description  Text            `json:"retryStatusDescription,omitempty"`
+	}
+	InquireResult struct {
+		Header             *ResponseHeader    `json:"responseHeader,omitempty"`
+		OrderId            Text               `json:"orderId,omitempty"`
+		AcquirerBank       Text               `json:"acquirerBankCode,omitempty"`
+		Status             Text               `json:"status,omitempty"`
+		Amount             Money              `json:"amount,omitempty"`
+		PointAmount        Money              `json:"pointAmount,omitempty"`
+		Currency           Text               `json:"currency,omitempty"`
+		ReconciliationDate Date               `json:"reconciliationDate,omitempty"`
+		Provisions         []*ProvisionRecord `json:"provisionList,omitempty"`
+	}
+	ProvisionRecord struct {
+		ProvisionType       Text  `json:"provisionType,omitempty"`
+		TransactionId       Text  `json:"transactionId,omitempty"`
+		RefNo               Text  `json:"referenceNumber,omitempty"`
+		Amount              Money `json:"amount,omitempty"`
+		PointAmount         Money `json:"pointAmount,omitempty"`
+		ApprovalCode        Text  `json:"approvalCode,omitempty"`
+		DateTime            Time  `json:"dateTime,omitempty"`
+		ReconciliationDate  Date  `json:"reconciliationDate,omitempty"`
+		ResponseCode        Text  `json:"responseCode,omitempty"`
+		ResponseDescription Text  `json:"responseDescription,omitempty"`
+	}
+	ThreeDSessionResult struct {
+		Header        *ResponseHeader `json:"responseHeader,omitempty"`
+		ThreeDSession Text            `json:"threeDSessionId,omitempty"`
+		Session       *PendingSession `json:"-"`
+	}
+	ThreeDResult struct {
+		Header         *ResponseHeader `json:"responseHeader,omitempty"`
+		CurrentStep    Text            `json:"currentStep,omitempty"`
+		MdErrorMessage Text            `json:"mdErrorMessage,omitempty"`
+		MdStatus       Text            `json:"mdStatus,omitempty"`
+		Operation      ThreeDOperation `json:"threeDOperationResult,omitempty"`
+	}
+	PaymentMethods struct {
+		Header        *ResponseHeader    `json:"responseHeader,omitempty"`
+		EulaID        Text               `json:"eulaID,omitempty"`
+		CardList      []*StoredCard      `json:"cardList,omitempty"`
+		MobilePayment *MobilePaymentInfo `json:"mobilePayment,omitempty"`
+	}
+	SummaryReconciliationResult struct {
+		Header               *ResponseHeader `json:"responseHeader,omitempty"`
+		ReconciliationResult Text            `json:"reconciliationResult,omitempty"`
+		ReconciliationDate   Date            `json:"reconciliationDate,omitempty"`
+		ReconciliationTotals
+	}
+	ReconciliationTotals struct {
+		SaleAmount     Money `json:"totalSaleAmount,omitempty"`
+		SaleCount      Int   `json:"totalSaleCount,omitempty"`
+		RefundAmount   Money `json:"totalRefundAmount,omitempty"`
+		RefundCount    Int   `json:"totalRefundCount,omitempty"`
+		ReverseAmount  Money `json:"totalReverseAmount,omitempty"`
+		ReverseCount   Int   `json:"totalReverseCount,omitempty"`
+		PreAuthAmount  Money `json:"totalPreAuthAmount,omitempty"`
+		PreAuthCount   Int   `json:"totalPreAuthCount,omitempty"`
+		PostAuthAmount Money `json:"totalPostAuthAmount,omitempty"`
+		PostAuthCount  Int   `json:"totalPostAuthCount,omitempty"`
+	}
+	ProvisionHistoryResult struct {
+		Header       *ResponseHeader  `json:"responseHeader,omitempty"`
+		NextIndex    Int              `json:"nextIndex,omitempty"`
+		Transactions []*HistoryRecord `json:"transactionList,omitempty"`
+	}
+	HistoryRecord struct {
+		OrderId            Text  `json:"orderId,omitempty"`
+		OrderDate          Time  `json:"orderDate,omitempty"`
+		RefNo              Text  `json:"referenceNumber,omitempty"`
+		OriginalRefNo      Text  `json:"originalReferenceNumber,omitempty"`
+		TransactionType    Text  `json:"transactionType,omitempty"`
+		MSisdn             Text  `json:"msisdn,omitempty"`
+		Amount             Money `json:"amount,omitempty"`
+		ApprovalCode       Text  `json:"approvalCode,omitempty"`
+		AcquirerBank       Text  `json:"acquirerBankCode,omitempty"`
+		ReconciliationDate Date  `json:"reconciliationDate,omitempty"`
+	}
+	PointBalanceResult struct {
+		Header       *ResponseHeader `json:"responseHeader,omitempty"`
+		PointBalance Money           `json:"pointBalance,omitempty"`
+	}
+	CardBinResult struct {
+		Header *ResponseHeader `json:"responseHeader,omitempty"`
+		Bins   []*CardBin      `json:"cardBinInformations,omitempty"`
+	}
+	CardBin struct {
+		BinValue         Text      `json:"binValue,omitempty"`
+		BankCode         Text      `json:"bankCode,omitempty"`
+		BankName         Text      `json:"bankName,omitempty"`
+		CardBrand        CardBrand `json:"cardBrand,omitempty"`
+		CardOrganization Text      `json:"cardOrganization,omitempty"`
+		CardType         CardType  `json:"cardType,omitempty"`
+		CommercialType   Text      `json:"commercialType,omitempty"`
+		Installments     []Int     `json:"installmentCounts,omitempty"`
+	}
+	CardsResult struct {
+		Header   *ResponseHeader `json:"responseHeader,omitempty"`
+		EulaID   Text            `json:"eulaId,omitempty"`
+		CardList []*StoredCard   `json:"cardList,omitempty"`
+	}
+	RegisterCardResult struct {
+		Header *ResponseHeader `json:"responseHeader,omitempty"`
+		CardId Text            `json:"cardId,omitempty"`
+	}
+	UpdateCardResult struct {
+		Header *ResponseHeader `json:"responseHeader,omitempty"`
+	}
+	DeleteCardResult struct {
+		Header *ResponseHeader `json:"responseHeader,omitempty"`
+	}
+	MobilePaymentResult struct {
+		Header *ResponseHeader `json:"responseHeader,omitempty"`
+	}
+	OTPResult struct {
+		Header     *ResponseHeader `json:"responseHeader,omitempty"`
+		Token      Text            `json:"token,omitempty" log:"secret"`
+		ExpireDate Time            `json:"expireDate,omitempty"`
+		RetryCount Int             `json:"remainingRetryCount,omitempty"`
+	}
+	ThreeDOperation struct {
+		Result      string `json:"threeDResult,omitempty"`
+		Description string `json:"threeDResultDescription,omitempty"`
+	}
+	StoredCard struct {
+		CardBrand         CardBrand `json:"cardBrand,omitempty"`
+		CardId            Text      `json:"cardId,omitempty"`
+		CardType          CardType  `json:"cardType,omitempty"`
+		MaskedCardNo      Text      `json:"maskedCardNo,omitempty"`
+		Alias             Text      `json:"alias,omitempty"`
+		ActivationDate    Time      `json:"activationDate,omitempty"`
+		IsDefault         bool      `json:"isDefault,omitempty"`
+		IsExpired         bool      `json:"isExpired,omitempty"`
+		ShowEulaId        bool      `json:"showEulaId,omitempty"`
+		IsThreeDValidated bool      `json:"isThreeDValidated,omitempty"`
+		IsOTPValidated    bool      `json:"isOTPValidated,omitempty"`
+	}
+	MobilePaymentInfo struct {
+		EulaId         Text  `json:"eulaId,omitempty"`
+		EulaUrl        Text  `json:"eulaUrl,omitempty"`
+		SignedEulaId   Text  `json:"signedEulaId,omitempty"`
+		StatementDate  Date  `json:"statementDate,omitempty"`
+		Limit          Money `json:"limit,omitempty"`
+		MaxLimit       Money `json:"maxLimit,omitempty"`
+		RemainingLimit Money `json:"remainingLimit,omitempty"`
+		IsDcbOpen      bool  `json:"isDcbOpen,omitempty"`
+		IsEulaExpired  bool  `json:"isEulaExpired,omitempty"`
 	}
 )
 
 type RequestHeader struct {
 	ApplicationName     string `json:"applicationName,omitempty"`
-	ApplicationPwd      string `json:"applicationPwd,omitempty"`
+	ApplicationPwd      string `json:"applicationPwd,omitempty" log:"secret"`
 	ClientIPAddress     string `json:"clientIPAddress,omitempty"`
 	TransactionDateTime string `json:"transactionDateTime,omitempty"`
 	TransactionId       string `json:"transactionId,omitempty"`
@@ -265,6 +502,23 @@ func Random(n int) string {
 	return string(bytes)
 }
 
+var lastDateTime atomic.Int64
+
+// datetime returns the current time in Paycell's format, unique within the
+// process since reference numbers are derived from it.
+func datetime() string {
+	for {
+		last := lastDateTime.Load()
+		now := time.Now().UnixMilli()
+		if now <= last {
+			now = last + 1
+		}
+		if lastDateTime.CompareAndSwap(last, now) {
+			return strings.ReplaceAll(time.UnixMilli(now).Format("20060102150405.000"), ".", "")
+		}
+	}
+}
+
 func Api(merchant, password, name string) (*API, *Request) {
 	api := new(API)
 	api.Merchant = merchant
@@ -286,6 +540,51 @@ func (api *API) SetMode(mode string) {
 	api.Mode = mode
 }
 
+func (api *API) SetHTTPClient(client Doer) {
+	api.HTTPClient = client
+}
+
+func (api *API) Use(middleware ...Middleware) {
+	api.Middleware = append(api.Middleware, middleware...)
+}
+
+func (api *API) SetEndPoints(base, token, form string) {
+	api.URLs = map[string]string{"": base, "_TOKEN": token, "_FORM": form}
+}
+
+func (api *API) SetSessionStore(store SessionStore, ttl time.Duration) {
+	api.Sessions, api.SessionTTL = store, ttl
+}
+
+func (api *API) SetFormPage(page FormPage) {
+	api.FormPage = &page
+}
+
+func (api *API) SetRetryPolicy(policy RetryPolicy) {
+	api.Retry = &policy
+}
+
+func (api *API) SetRecovery(recovery Recovery) {
+	api.Recovery = &recovery
+}
+
+func (api *API) SetLogger(logger *slog.Logger) {
+	api.Logger = logger
+}
+
+func (api *API) SetTracer(tracer Tracer) {
+	api.Tracer = tracer
+}
+
+func (api *API) SetMetrics(metrics Metrics) {
+	api.Metrics = metrics
+}
+
+func (api *API) SetDeadlineBudget(total, token time.Duration) {
+	api.Timeout = total
+	api.TokenTimeout = token
+}
+
 func (api *API) SetIPAddress(ip string) {
 	api.IPv4 = ip
 }
@@ -294,558 +593,725 @@ func (api *API) SetPhoneNumber(isdn string) {
 	api.ISDN = isdn
 }
 
-func (api *API) SetAmount(total string, currency string) {
-	api.Amount = strings.ReplaceAll(total, ".", "")
-	api.Currency = currency
+func (api *API) SetAmount(total string, currency string) error {
+	amount, err := ParseMoney(total, currency)
+	if err != nil {
+		return err
+	}
+	api.SetMoney(amount)
+	return nil
+}
+
+// SetPointAmount sets the part of the amount paid with Turkcell points.
+func (api *API) SetPointAmount(points Money) {
+	api.Points = points
+}
+
+func (api *API) SetMoney(amount Money) {
+	api.Money = amount
+	api.Amount, api.Currency = amount.Minor(), amount.Currency
 }
 
 func (req *Request) SetCardNumber(number string) {
-	req.CardToken.CardNumber = number
+	req.CardToken.SetCardNumber(number)
 }
 
 func (req *Request) SetCardExpiry(month, year string) {
-	req.CardToken.CardMonth = month
-	req.CardToken.CardYear = year
+	req.CardToken.SetCardExpiry(month, year)
 }
 
 func (req *Request) SetCardCode(code string) {
-	req.CardToken.CardCode = code
+	req.CardToken.SetCardCode(code)
+}
+
+func (req *CardTokenRequest) SetCardNumber(number string) {
+	req.CardNumber = number
+}
+
+func (req *CardTokenRequest) SetCardExpiry(month, year string) {
+	req.CardMonth = month
+	req.CardYear = year
+}
+
+func (req *CardTokenRequest) SetCardCode(code string) {
+	req.CardCode = code
+}
+
+func (api *API) client() *Client {
+	return &Client{
+		mode:         api.Mode,
+		merchant:     api.Merchant,
+		password:     api.Password,
+		name:         api.Name,
+		key:          api.Key,
+		prefix:       api.Prefix,
+		httpClient:   api.HTTPClient,
+		middleware:   api.Middleware,
+		urls:         api.URLs,
+		sessions:     api.Sessions,
+		sessionTTL:   api.SessionTTL,
+		formPage:     api.FormPage,
+		retry:        api.Retry,
+		recovery:     api.Recovery,
+		logger:       api.Logger,
+		tracer:       api.Tracer,
+		metrics:      api.Metrics,
+		timeout:      api.Timeout,
+		tokenTimeout: api.TokenTimeout,
+	}
+}
+
+func (api *API) params() Params {
+	return Params{MSISDN: api.ISDN, ClientIP: api.IPv4, Amount: api.amount(), PointAmount: api.Points}
+}
+
+// amount converts the deprecated Amount and Currency fields. An Amount that
+// is not a whole number of minor units is left at zero so validation
+// rejects it.
+func (api *API) amount() Money {
+	if api.Money != (Money{}) || api.Amount == "" {
+		return api.Money
+	}
+	minor, _ := strconv.ParseInt(strings.TrimSpace(api.Amount), 10, 64)
+	return NewMoney(minor, api.Currency)
 }
 
 func (api *API) Hash(res Response) string {
-	hashdata := SHA256(strings.ToUpper(api.Name + res.CardToken.Header.TransactionId + res.CardToken.Header.ResponseDateTime + res.CardToken.Header.ResponseCode + res.CardToken.Token + api.Key + SHA256(strings.ToUpper(api.Password+api.Name))))
-	return hashdata
+	return api.client().hash(&res.CardToken)
 }
 
 func (api *API) PreAuth(ctx context.Context, req *Request) (res Response, err error) {
-	token, err := api.CardToken(context.Background(), req)
-	if err != nil {
-		res.Provision.Header = new(ResponseHeader)
-		return res, err
-	}
-	req.Provision.CardToken = token.CardToken.Token
-	req.Provision.Header.ClientIPAddress = api.IPv4
-	req.Provision.Header.ApplicationName = api.Name
-	req.Provision.Header.ApplicationPwd = api.Password
-	req.Provision.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.Provision.Header.TransactionId = Random(20)
-	req.Provision.MSisdn = api.ISDN
-	req.Provision.MerchantCode = api.Merchant
-	req.Provision.RefNo = api.Prefix + fmt.Sprintf("%v", req.Provision.Header.TransactionDateTime)
-	req.Provision.Amount = api.Amount
-	req.Provision.Currency = api.Currency
-	req.Provision.PaymentType = "PREAUTH"
-	payload, err := json.Marshal(req.Provision)
-	if err != nil {
-		return res, err
+	req.Provision.Card = &req.CardToken
+	r, err := api.client().PreAuth(ctx, &req.Provision, api.params())
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) Auth(ctx context.Context, req *Request) (res Response, err error) {
+	req.Provision.Card = &req.CardToken
+	r, err := api.client().Auth(ctx, &req.Provision, api.params())
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) PreAuth3Dinit(ctx context.Context, req *Request) (res Response, err error) {
+	req.ThreeDSession.Card = &req.CardToken
+	r, err := api.client().PreAuth3Dinit(ctx, &req.ThreeDSession, api.params())
+	res.ThreeDSession = *r
+	return res, err
+}
+
+func (api *API) Auth3Dinit(ctx context.Context, req *Request) (res Response, err error) {
+	req.ThreeDSession.Card = &req.CardToken
+	r, err := api.client().Auth3Dinit(ctx, &req.ThreeDSession, api.params())
+	res.ThreeDSession = *r
+	return res, err
+}
+
+func (api *API) PreAuth3D(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().PreAuth3D(ctx, &req.ThreeDResult, api.params())
+	res.ThreeDResult = *r
+	return res, err
+}
+
+func (api *API) Auth3D(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().Auth3D(ctx, &req.ThreeDResult, api.params())
+	res.ThreeDResult = *r
+	return res, err
+}
+
+func (api *API) Complete3D(ctx context.Context, session *PendingSession) (res Response, err error) {
+	r, err := api.client().Complete3D(ctx, session)
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) Complete3DSession(ctx context.Context, sessionId string) (res Response, err error) {
+	r, err := api.client().Complete3DSession(ctx, sessionId)
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) CompletePreAuth3D(ctx context.Context, session *PendingSession) (res Response, err error) {
+	r, err := api.client().CompletePreAuth3D(ctx, session)
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) PreAuth3Dhtml(ctx context.Context, req *Request) (string, error) {
+	return api.client().PreAuth3Dhtml(ctx, &req.ThreeDForm)
+}
+
+func (api *API) Auth3Dhtml(ctx context.Context, req *Request) (string, error) {
+	return api.client().Auth3Dhtml(ctx, &req.ThreeDForm)
+}
+
+func (api *API) PostAuth(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().PostAuth(ctx, &req.Provision, api.params())
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) Refund(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().Refund(ctx, &req.Refund, api.params())
+	res.Refund = *r
+	return res, err
+}
+
+func (api *API) Cancel(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().Cancel(ctx, &req.Cancel, api.params())
+	res.Cancel = *r
+	return res, err
+}
+
+func (api *API) Inquire(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().Inquire(ctx, &req.Inquire, api.params())
+	res.Inquire = *r
+	return res, err
+}
+
+func (api *API) Transaction3D(ctx context.Context, req *Request) (string, error) {
+	return api.client().Transaction3D(ctx, &req.ThreeDForm)
+}
+
+func (api *API) Render3D(ctx context.Context, req *Request, page *FormPage) (string, error) {
+	return api.client().Render3D(ctx, &req.ThreeDForm, page)
+}
+
+func (api *API) Serve3D(ctx context.Context, w http.ResponseWriter, req *Request, page *FormPage) error {
+	return api.client().Serve3D(ctx, w, &req.ThreeDForm, page)
+}
+
+func (api *API) CardToken(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().CardToken(ctx, &req.CardToken)
+	res.CardToken = *r
+	return res, err
+}
+
+func (api *API) GetPaymentMethods(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().GetPaymentMethods(ctx, &req.PaymentMethods, api.params())
+	res.PaymentMethods = *r
+	return res, err
+}
+
+func (api *API) OpenMobilePayment(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().OpenMobilePayment(ctx, &req.MobilePayment, api.params())
+	res.MobilePayment = *r
+	return res, err
+}
+
+func (api *API) SendOTP(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().SendOTP(ctx, &req.OTP, api.params())
+	res.OTP = *r
+	return res, err
+}
+
+func (api *API) ValidateOTP(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().ValidateOTP(ctx, &req.OTP, api.params())
+	res.OTP = *r
+	return res, err
+}
+
+func (c *Client) hash(res *CardTokenResult) string {
+	if res.Header == nil {
+		return ""
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/provision/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
+	hashdata := SHA256(strings.ToUpper(c.name + res.Header.TransactionId + res.Header.ResponseDateTime + res.Header.ResponseCode + res.Token + c.key + SHA256(strings.ToUpper(c.password+c.name))))
+	return hashdata
+}
+
+func (c *Client) endpoint(kind string) string {
+	if url, ok := c.urls[kind]; ok {
+		return url
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
-	if err != nil {
-		return res, err
+	return EndPoints[c.mode+kind]
+}
+
+func (c *Client) transport() http.RoundTripper {
+	client := c.httpClient
+	if client == nil {
+		client = DefaultClient
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.Provision)
-	if code, err := strconv.Atoi(res.Provision.Header.ResponseCode); err == nil && code == 0 {
-		res.Provision.RefNo = req.Provision.RefNo
-		return res, nil
+	var rt http.RoundTripper = RoundTripperFunc(client.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
 	}
-	return res, errors.New(res.Provision.Header.ResponseDescription)
+	return rt
 }
 
-func (api *API) Auth(ctx context.Context, req *Request) (res Response, err error) {
-	token, err := api.CardToken(context.Background(), req)
-	if err != nil {
-		res.Provision.Header = new(ResponseHeader)
-		return res, err
-	}
-	req.Provision.CardToken = token.CardToken.Token
-	req.Provision.Header.ClientIPAddress = api.IPv4
-	req.Provision.Header.ApplicationName = api.Name
-	req.Provision.Header.ApplicationPwd = api.Password
-	req.Provision.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.Provision.Header.TransactionId = Random(20)
-	req.Provision.MSisdn = api.ISDN
-	req.Provision.MerchantCode = api.Merchant
-	req.Provision.RefNo = api.Prefix + fmt.Sprintf("%v", req.Provision.Header.TransactionDateTime)
-	req.Provision.Amount = api.Amount
-	req.Provision.Currency = api.Currency
-	req.Provision.PaymentType = "SALE"
-	payload, err := json.Marshal(req.Provision)
+func (c *Client) send(ctx context.Context, url string, in, out any) (status int, err error) {
+	defer func(start time.Time) {
+		elapsed := time.Since(start)
+		c.log(ctx, url, in, out, status, elapsed, err)
+		var code string
+		var invalid *ResponseError
+		switch {
+		case errors.As(err, &invalid):
+			code = "invalid"
+		case err != nil:
+			code = "error"
+		default:
+			if header := responseHeader(out); header != nil {
+				code = header.ResponseCode
+			}
+		}
+		c.stats().Request(operation(url), c.environment(), code, elapsed)
+	}(time.Now())
+	payload, err := json.Marshal(in)
 	if err != nil {
-		return res, err
+		return status, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/provision/", bytes.NewReader(payload))
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
 	if err != nil {
-		return res, err
+		return status, err
 	}
 	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	response, err := c.transport().RoundTrip(request)
 	if err != nil {
-		return res, err
+		return status, err
 	}
 	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.Provision)
-	if code, err := strconv.Atoi(res.Provision.Header.ResponseCode); err == nil && code == 0 {
-		res.Provision.RefNo = req.Provision.RefNo
-		return res, nil
-	}
-	return res, errors.New(res.Provision.Header.ResponseDescription)
+	return response.StatusCode, decode(url, response, out)
 }
 
-func (api *API) PreAuth3Dinit(ctx context.Context, req *Request) (res Response, err error) {
-	token, err := api.CardToken(context.Background(), req)
-	if err != nil {
-		res.ThreeDSession.Header = new(ResponseHeader)
+func (c *Client) PreAuth(ctx context.Context, req *ProvisionRequest, p Params) (res *ProvisionResult, err error) {
+	ctx, span := c.trace(ctx, "PreAuth")
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ProvisionResult)
+	if err := p.Amount.Validate(); err != nil {
 		return res, err
 	}
-	req.ThreeDSession.CardToken = token.CardToken.Token
-	req.ThreeDSession.Header.ClientIPAddress = api.IPv4
-	req.ThreeDSession.Header.ApplicationName = api.Name
-	req.ThreeDSession.Header.ApplicationPwd = api.Password
-	req.ThreeDSession.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.ThreeDSession.Header.TransactionId = Random(20)
-	req.ThreeDSession.Target = "MERCHANT"
-	req.ThreeDSession.Transaction = "PREAUTH"
-	req.ThreeDSession.MSisdn = api.ISDN
-	req.ThreeDSession.MerchantCode = api.Merchant
-	req.ThreeDSession.Amount = api.Amount
-	req.ThreeDSession.Currency = api.Currency
-	payload, err := json.Marshal(req.ThreeDSession)
+	token, err := c.tokenize(ctx, req.Card)
 	if err != nil {
+		res.Header = new(ResponseHeader)
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/getThreeDSession/", bytes.NewReader(payload))
-	if err != nil {
+	req.CardToken = token.Token
+	return c.provision(ctx, "PreAuth", "PREAUTH", "", req, p)
+}
+
+func (c *Client) Auth(ctx context.Context, req *ProvisionRequest, p Params) (res *ProvisionResult, err error) {
+	ctx, span := c.trace(ctx, "Auth")
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ProvisionResult)
+	if err := p.Amount.Validate(); err != nil {
 		return res, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	token, err := c.tokenize(ctx, req.Card)
 	if err != nil {
+		res.Header = new(ResponseHeader)
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.ThreeDSession)
-	if code, err := strconv.Atoi(res.ThreeDSession.Header.ResponseCode); err == nil && code == 0 {
-		return res, nil
-	}
-	return res, errors.New(res.ThreeDSession.Header.ResponseDescription)
+	req.CardToken = token.Token
+	return c.provision(ctx, "Auth", "SALE", "", req, p)
 }
 
-func (api *API) Auth3Dinit(ctx context.Context, req *Request) (res Response, err error) {
-	token, err := api.CardToken(context.Background(), req)
-	if err != nil {
-		res.ThreeDSession.Header = new(ResponseHeader)
+func (c *Client) PreAuth3Dinit(ctx context.Context, req *ThreeDSessionRequest, p Params) (res *ThreeDSessionResult, err error) {
+	ctx, span := c.trace(ctx, "PreAuth3Dinit")
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ThreeDSessionResult)
+	if err := p.Amount.Validate(); err != nil {
 		return res, err
 	}
-	req.ThreeDSession.CardToken = token.CardToken.Token
-	req.ThreeDSession.Header.ClientIPAddress = api.IPv4
-	req.ThreeDSession.Header.ApplicationName = api.Name
-	req.ThreeDSession.Header.ApplicationPwd = api.Password
-	req.ThreeDSession.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.ThreeDSession.Header.TransactionId = Random(20)
-	req.ThreeDSession.Target = "MERCHANT"
-	req.ThreeDSession.Transaction = "AUTH"
-	req.ThreeDSession.MSisdn = api.ISDN
-	req.ThreeDSession.MerchantCode = api.Merchant
-	req.ThreeDSession.Amount = api.Amount
-	req.ThreeDSession.Currency = api.Currency
-	payload, err := json.Marshal(req.ThreeDSession)
+	token, err := c.tokenize(ctx, req.Card)
 	if err != nil {
+		res.Header = new(ResponseHeader)
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/getThreeDSession/", bytes.NewReader(payload))
-	if err != nil {
+	req.CardToken = token.Token
+	return c.threeDSession(ctx, "PreAuth3Dinit", "PREAUTH", req, p)
+}
+
+func (c *Client) Auth3Dinit(ctx context.Context, req *ThreeDSessionRequest, p Params) (res *ThreeDSessionResult, err error) {
+	ctx, span := c.trace(ctx, "Auth3Dinit")
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ThreeDSessionResult)
+	if err := p.Amount.Validate(); err != nil {
 		return res, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	token, err := c.tokenize(ctx, req.Card)
 	if err != nil {
+		res.Header = new(ResponseHeader)
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.ThreeDSession)
-	if code, err := strconv.Atoi(res.ThreeDSession.Header.ResponseCode); err == nil && code == 0 {
-		return res, nil
-	}
-	return res, errors.New(res.ThreeDSession.Header.ResponseDescription)
+	req.CardToken = token.Token
+	return c.threeDSession(ctx, "Auth3Dinit", "AUTH", req, p)
 }
 
-func (api *API) PreAuth3D(ctx context.Context, req *Request) (res Response, err error) {
-	req.ThreeDResult.Header.ClientIPAddress = api.IPv4
-	req.ThreeDResult.Header.ApplicationName = api.Name
-	req.ThreeDResult.Header.ApplicationPwd = api.Password
-	req.ThreeDResult.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.ThreeDResult.Header.TransactionId = Random(20)
-	req.ThreeDResult.MSisdn = api.ISDN
-	req.ThreeDResult.MerchantCode = api.Merchant
-	payload, err := json.Marshal(req.ThreeDResult)
-	if err != nil {
+func (c *Client) threeDSession(ctx context.Context, operation, transaction string, req *ThreeDSessionRequest, p Params) (res *ThreeDSessionResult, err error) {
+	res = new(ThreeDSessionResult)
+	if err := p.validatePoints(); err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/getThreeDSessionResult/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
-	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.Target = "MERCHANT"
+	req.Transaction = transaction
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	refNo := c.prefix + req.Header.TransactionDateTime
+	req.RefNo = refNo
+	req.Amount = p.Amount.Minor()
+	req.Currency = p.Amount.Currency
+	if p.Installment > 0 {
+		req.Installment = strconv.Itoa(p.Installment)
+	}
+	if !p.PointAmount.IsZero() {
+		req.PointAmount = p.PointAmount.Minor()
+	}
+	status, err := c.send(ctx, c.endpoint("")+"/getThreeDSession/", req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.ThreeDResult)
-	if code, err := strconv.Atoi(res.ThreeDResult.Operation.Result); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		cardId, _ := req.CardId.(string)
+		cardToken, _ := req.CardToken.(string)
+		res.Session = &PendingSession{
+			SessionId:   string(res.ThreeDSession),
+			CardId:      cardId,
+			CardToken:   cardToken,
+			RefNo:       refNo,
+			Transaction: transaction,
+			Params:      p,
+		}
+		if err := c.saveSession(ctx, res.Session); err != nil {
+			return res, err
+		}
 		return res, nil
 	}
-	return res, errors.New(res.ThreeDResult.Operation.Description)
+	return res, newError(operation, status, res.Header)
 }
 
-func (api *API) Auth3D(ctx context.Context, req *Request) (res Response, err error) {
-	req.ThreeDResult.Header.ClientIPAddress = api.IPv4
-	req.ThreeDResult.Header.ApplicationName = api.Name
-	req.ThreeDResult.Header.ApplicationPwd = api.Password
-	req.ThreeDResult.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.ThreeDResult.Header.TransactionId = Random(20)
-	req.ThreeDResult.MSisdn = api.ISDN
-	req.ThreeDResult.MerchantCode = api.Merchant
-	payload, err := json.Marshal(req.ThreeDResult)
+func (c *Client) PreAuth3D(ctx context.Context, req *ThreeDResultRequest, p Params) (res *ThreeDResult, err error) {
+	ctx, span := c.trace(ctx, "PreAuth3D")
+	defer func() { span.end(req, res, err) }()
+	res = new(ThreeDResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getThreeDSessionResult/", req, res)
 	if err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/getThreeDSessionResult/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err != nil || code != 0 {
+		return res, newError("PreAuth3D", status, res.Header)
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	if code, err := strconv.Atoi(res.Operation.Result); err == nil && code == 0 {
+		c.stats().ThreeD(true)
+		return res, nil
+	}
+	c.stats().ThreeD(false)
+	return res, &Error{
+		Operation:     "PreAuth3D",
+		Code:          res.Operation.Result,
+		Description:   res.Operation.Description,
+		TransactionId: res.Header.TransactionId,
+		StatusCode:    status,
+		Category:      ErrThreeDSecure,
+	}
+}
+
+func (c *Client) Auth3D(ctx context.Context, req *ThreeDResultRequest, p Params) (res *ThreeDResult, err error) {
+	ctx, span := c.trace(ctx, "Auth3D")
+	defer func() { span.end(req, res, err) }()
+	res = new(ThreeDResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getThreeDSessionResult/", req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.ThreeDResult)
-	if code, err := strconv.Atoi(res.ThreeDResult.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		code, err := strconv.Atoi(res.Operation.Result)
+		c.stats().ThreeD(err == nil && code == 0)
 		return res, nil
 	}
-	return res, errors.New(res.ThreeDResult.Header.ResponseDescription)
+	return res, newError("Auth3D", status, res.Header)
 }
 
-func (api *API) PreAuth3Dhtml(ctx context.Context, req *Request) (string, error) {
-	return api.Transaction3D(ctx, req)
+func (c *Client) PreAuth3Dhtml(ctx context.Context, req *ThreeDFormRequest) (string, error) {
+	return c.Transaction3D(ctx, req)
 }
 
-func (api *API) Auth3Dhtml(ctx context.Context, req *Request) (string, error) {
-	return api.Transaction3D(ctx, req)
+func (c *Client) Auth3Dhtml(ctx context.Context, req *ThreeDFormRequest) (string, error) {
+	return c.Transaction3D(ctx, req)
 }
 
-func (api *API) PostAuth(ctx context.Context, req *Request) (res Response, err error) {
-	req.Provision.Header.ClientIPAddress = api.IPv4
-	req.Provision.Header.ApplicationName = api.Name
-	req.Provision.Header.ApplicationPwd = api.Password
-	req.Provision.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.Provision.Header.TransactionId = Random(20)
-	req.Provision.MSisdn = api.ISDN
-	req.Provision.MerchantCode = api.Merchant
-	req.Provision.RefNo = api.Prefix + fmt.Sprintf("%v", req.Provision.Header.TransactionDateTime)
-	req.Provision.Amount = api.Amount
-	req.Provision.Currency = api.Currency
-	req.Provision.PaymentType = "POSTAUTH"
-	payload, err := json.Marshal(req.Provision)
-	if err != nil {
-		return res, err
+func (c *Client) PostAuth(ctx context.Context, req *ProvisionRequest, p Params) (res *ProvisionResult, err error) {
+	ctx, span := c.trace(ctx, "PostAuth")
+	defer func() { span.end(req, res, err) }()
+	if err := p.Amount.Validate(); err != nil {
+		return new(ProvisionResult), err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/provision/", bytes.NewReader(payload))
-	if err != nil {
+	return c.provision(ctx, "PostAuth", "POSTAUTH", "", req, p)
+}
+
+func (c *Client) provision(ctx context.Context, operation, paymentType, refNo string, req *ProvisionRequest, p Params) (res *ProvisionResult, err error) {
+	res = new(ProvisionResult)
+	if err := p.validatePoints(); err != nil {
 		return res, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	if refNo == "" {
+		refNo = c.prefix + req.Header.TransactionDateTime
+	}
+	req.RefNo = refNo
+	req.Amount = p.Amount.Minor()
+	req.Currency = p.Amount.Currency
+	req.PaymentType = paymentType
+	if p.Installment > 0 {
+		req.Installment = strconv.Itoa(p.Installment)
+	}
+	if !p.PointAmount.IsZero() {
+		req.PointAmount = p.PointAmount.Minor()
+	}
+	status, err := c.send(ctx, c.endpoint("")+"/provision/", req, res)
+	if c.recovery != nil && ambiguous(status, err, res.Header) {
+		return c.recoverProvision(ctx, operation, refNo, res, p, status, err)
+	}
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.Provision)
-	if code, err := strconv.Atoi(res.Provision.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		res.RefNo = Text(refNo)
 		return res, nil
 	}
-	return res, errors.New(res.Provision.Header.ResponseDescription)
+	return res, newError(operation, status, res.Header)
 }
 
-func (api *API) Refund(ctx context.Context, req *Request) (res Response, err error) {
-	req.Refund.Header.ClientIPAddress = api.IPv4
-	req.Refund.Header.ApplicationName = api.Name
-	req.Refund.Header.ApplicationPwd = api.Password
-	req.Refund.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.Refund.Header.TransactionId = Random(20)
-	req.Refund.MSisdn = api.ISDN
-	req.Refund.MerchantCode = api.Merchant
-	req.Refund.RefNo = api.Prefix + fmt.Sprintf("%v", req.Refund.Header.TransactionDateTime)
-	req.Refund.Amount = api.Amount
-	req.Refund.Currency = api.Currency
-	payload, err := json.Marshal(req.Refund)
-	if err != nil {
+func (c *Client) Refund(ctx context.Context, req *RefundRequest, p Params) (res *RefundResult, err error) {
+	ctx, span := c.trace(ctx, "Refund")
+	defer func() { span.end(req, res, err) }()
+	res = new(RefundResult)
+	if err := p.validate(); err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/refund/", bytes.NewReader(payload))
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	req.RefNo = c.prefix + fmt.Sprintf("%v", req.Header.TransactionDateTime)
+	req.Amount = p.Amount.Minor()
+	req.Currency = p.Amount.Currency
+	if !p.PointAmount.IsZero() {
+		req.PointAmount = p.PointAmount.Minor()
+	}
+	status, err := c.send(ctx, c.endpoint("")+"/refund/", req, res)
 	if err != nil {
 		return res, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
-	if err != nil {
-		return res, err
-	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.Refund)
-	if code, err := strconv.Atoi(res.Refund.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
 		return res, nil
 	}
-	return res, errors.New(res.Refund.Header.ResponseDescription)
+	return res, newError("Refund", status, res.Header)
 }
 
-func (api *API) Cancel(ctx context.Context, req *Request) (res Response, err error) {
-	req.Cancel.Header.ClientIPAddress = api.IPv4
-	req.Cancel.Header.ApplicationName = api.Name
-	req.Cancel.Header.ApplicationPwd = api.Password
-	req.Cancel.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.Cancel.Header.TransactionId = Random(20)
-	req.Cancel.MSisdn = api.ISDN
-	req.Cancel.MerchantCode = api.Merchant
-	req.Cancel.RefNo = api.Prefix + fmt.Sprintf("%v", req.Cancel.Header.TransactionDateTime)
-	payload, err := json.Marshal(req.Cancel)
+func (c *Client) Cancel(ctx context.Context, req *CancelRequest, p Params) (res *CancelResult, err error) {
+	ctx, span := c.trace(ctx, "Cancel")
+	defer func() { span.end(req, res, err) }()
+	res = new(CancelResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	req.RefNo = c.prefix + fmt.Sprintf("%v", req.Header.TransactionDateTime)
+	status, err := c.send(ctx, c.endpoint("")+"/reverse/", req, res)
 	if err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/reverse/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	return res, newError("Cancel", status, res.Header)
+}
+
+func (c *Client) Inquire(ctx context.Context, req *InquireRequest, p Params) (res *InquireResult, err error) {
+	ctx, span := c.trace(ctx, "Inquire")
+	defer func() { span.end(req, res, err) }()
+	res = new(InquireResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.MerchantCode = c.merchant
+	req.RefNo = c.prefix + fmt.Sprintf("%v", req.Header.TransactionDateTime)
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/inquire/", req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.Cancel)
-	if code, err := strconv.Atoi(res.Cancel.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		if res.Currency != "" {
+			res.Amount.Currency = string(res.Currency)
+			res.PointAmount.Currency = string(res.Currency)
+			for _, record := range res.Provisions {
+				record.Amount.Currency = string(res.Currency)
+				record.PointAmount.Currency = string(res.Currency)
+			}
+		}
 		return res, nil
 	}
-	return res, errors.New(res.Cancel.Header.ResponseDescription)
+	return res, newError("Inquire", status, res.Header)
 }
 
-func (api *API) Transaction3D(ctx context.Context, req *Request) (res string, err error) {
-	payload, err := QueryString(req.ThreeDForm)
+// Transaction3D returns the base64 encoded auto-submit page; see Render3D
+// and Serve3D for raw HTML and direct output.
+func (c *Client) Transaction3D(ctx context.Context, req *ThreeDFormRequest) (string, error) {
+	html, err := c.Render3D(ctx, req, nil)
 	if err != nil {
-		return res, err
+		return "", err
 	}
-	html := []string{}
-	html = append(html, `<!DOCTYPE html>`)
-	html = append(html, `<html>`)
-	html = append(html, `<head>`)
-	html = append(html, `<meta http-equiv="Content-Type" content="text/html; charset=utf-8">`)
-	html = append(html, `<script type="text/javascript">function submitonload() {document.payment.submit();document.getElementById('button').remove();document.getElementById('body').insertAdjacentHTML("beforeend", "Lütfen bekleyiniz...");}</script>`)
-	html = append(html, `</head>`)
-	html = append(html, `<body onload="javascript:submitonload();" id="body" style="text-align:center;margin:10px;font-family:Arial;font-weight:bold;">`)
-	html = append(html, `<form action="`+EndPoints[api.Mode+"_FORM"]+`" method="post" name="payment">`)
-	for k := range payload {
-		html = append(html, `<input type="hidden" name="`+k+`" value="`+payload.Get(k)+`">`)
-	}
-	html = append(html, `<input type="submit" value="Gönder" id="button">`)
-	html = append(html, `</form>`)
-	html = append(html, `</body>`)
-	html = append(html, `</html>`)
-	res = B64(strings.Join(html, "\n"))
-	return res, err
+	return B64(html), nil
 }
 
-func (api *API) CardToken(ctx context.Context, req *Request) (res Response, err error) {
-	req.CardToken.Header.ApplicationName = api.Name
-	req.CardToken.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.CardToken.Header.TransactionId = Random(20)
-	req.CardToken.Hash = SHA256(strings.ToUpper(api.Name + req.CardToken.Header.TransactionId + req.CardToken.Header.TransactionDateTime + api.Key + SHA256(strings.ToUpper(api.Password+api.Name))))
-	payload, err := json.Marshal(req.CardToken)
-	if err != nil {
-		return res, err
-	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode+"_TOKEN"], bytes.NewReader(payload))
-	if err != nil {
-		return res, err
-	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+func (c *Client) CardToken(ctx context.Context, req *CardTokenRequest) (res *CardTokenResult, err error) {
+	ctx, span := c.trace(ctx, "CardToken")
+	defer func() { span.end(req, res, err) }()
+	res = new(CardTokenResult)
+	if req == nil {
+		req = new(CardTokenRequest)
+	}
+	req.Header.ApplicationName = c.name
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.Hash = SHA256(strings.ToUpper(c.name + req.Header.TransactionId + req.Header.TransactionDateTime + c.key + SHA256(strings.ToUpper(c.password+c.name))))
+	status, err := c.sendIdempotent(ctx, c.endpoint("_TOKEN"), req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.CardToken)
-	if code, err := strconv.Atoi(res.CardToken.Header.ResponseCode); err == nil && code == 0 {
-		if res.CardToken.Hash != api.Hash(res) {
-			return res, errors.New("INVALID_HASH")
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		if res.Hash != c.hash(res) {
+			c.stats().HashFailure(operation(c.endpoint("_TOKEN")))
+			e := newError("CardToken", status, res.Header)
+			e.Description, e.Category = "INVALID_HASH", ErrHashMismatch
+			return res, e
 		}
 		return res, nil
 	}
-	return res, errors.New(res.CardToken.Header.ResponseDescription)
+	return res, newError("CardToken", status, res.Header)
 }
 
-func (api *API) GetPaymentMethods(ctx context.Context, req *Request) (res Response, err error) {
-	req.PaymentMethods.MSisdn = api.ISDN
-	req.PaymentMethods.Header.ClientIPAddress = api.IPv4
-	req.PaymentMethods.Header.ApplicationName = api.Name
-	req.PaymentMethods.Header.ApplicationPwd = api.Password
-	req.PaymentMethods.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.PaymentMethods.Header.TransactionId = Random(20)
-	payload, err := json.Marshal(req.PaymentMethods)
-	if err != nil {
-		return res, err
-	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/getPaymentMethods/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
-	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+func (c *Client) GetPaymentMethods(ctx context.Context, req *PaymentMethodsRequest, p Params) (res *PaymentMethods, err error) {
+	ctx, span := c.trace(ctx, "GetPaymentMethods")
+	defer func() { span.end(req, res, err) }()
+	res = new(PaymentMethods)
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getPaymentMethods/", req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.PaymentMethods)
-	if code, err := strconv.Atoi(res.PaymentMethods.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
 		return res, nil
 	}
-	return res, errors.New(res.PaymentMethods.Header.ResponseDescription)
+	return res, newError("GetPaymentMethods", status, res.Header)
 }
 
-func (api *API) OpenMobilePayment(ctx context.Context, req *Request) (res Response, err error) {
-	req.MobilePayment.Header.ClientIPAddress = api.IPv4
-	req.MobilePayment.Header.ApplicationName = api.Name
-	req.MobilePayment.Header.ApplicationPwd = api.Password
-	req.MobilePayment.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.MobilePayment.Header.TransactionId = Random(20)
-	req.MobilePayment.MSisdn = api.ISDN
-	payload, err := json.Marshal(req.MobilePayment)
-	if err != nil {
-		return res, err
-	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/openMobilePayment/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
-	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+func (c *Client) OpenMobilePayment(ctx context.Context, req *MobilePaymentRequest, p Params) (res *MobilePaymentResult, err error) {
+	ctx, span := c.trace(ctx, "OpenMobilePayment")
+	defer func() { span.end(req, res, err) }()
+	res = new(MobilePaymentResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	status, err := c.send(ctx, c.endpoint("")+"/openMobilePayment/", req, res)
 	if err != nil {
 		return res, err
 	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.MobilePayment)
-	if code, err := strconv.Atoi(res.MobilePayment.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
 		return res, nil
 	}
-	return res, errors.New(res.MobilePayment.Header.ResponseDescription)
+	return res, newError("OpenMobilePayment", status, res.Header)
 }
 
-func (api *API) SendOTP(ctx context.Context, req *Request) (res Response, err error) {
-	req.OTP.Header.ClientIPAddress = api.IPv4
-	req.OTP.Header.ApplicationName = api.Name
-	req.OTP.Header.ApplicationPwd = api.Password
-	req.OTP.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.OTP.Header.TransactionId = Random(20)
-	req.OTP.MSisdn = api.ISDN
-	req.OTP.RefNo = Random(20)
-	req.OTP.Amount = api.Amount
-	req.OTP.Currency = api.Currency
-	payload, err := json.Marshal(req.OTP)
-	if err != nil {
+func (c *Client) SendOTP(ctx context.Context, req *OTPRequest, p Params) (res *OTPResult, err error) {
+	ctx, span := c.trace(ctx, "SendOTP")
+	defer func() { span.end(req, res, err) }()
+	res = new(OTPResult)
+	if err := p.Amount.Validate(); err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/sendOTP/", bytes.NewReader(payload))
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.RefNo = Random(20)
+	req.Amount = p.Amount.Minor()
+	req.Currency = p.Amount.Currency
+	status, err := c.send(ctx, c.endpoint("")+"/sendOTP/", req, res)
 	if err != nil {
 		return res, err
 	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
-	if err != nil {
-		return res, err
-	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.OTP)
-	if code, err := strconv.Atoi(res.OTP.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
 		return res, nil
 	}
-	return res, errors.New(res.OTP.Header.ResponseDescription)
+	return res, newError("SendOTP", status, res.Header)
 }
 
-func (api *API) ValidateOTP(ctx context.Context, req *Request) (res Response, err error) {
-	req.OTP.Header.ClientIPAddress = api.IPv4
-	req.OTP.Header.ApplicationName = api.Name
-	req.OTP.Header.ApplicationPwd = api.Password
-	req.OTP.Header.TransactionDateTime = strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
-	req.OTP.Header.TransactionId = Random(20)
-	req.OTP.MSisdn = api.ISDN
-	req.OTP.RefNo = Random(20)
-	req.OTP.Amount = api.Amount
-	req.OTP.Currency = api.Currency
-	payload, err := json.Marshal(req.OTP)
+func (c *Client) ValidateOTP(ctx context.Context, req *OTPRequest, p Params) (res *OTPResult, err error) {
+	ctx, span := c.trace(ctx, "ValidateOTP")
+	defer func() { span.end(req, res, err) }()
+	res = new(OTPResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MSisdn = p.MSISDN
+	req.RefNo = Random(20)
+	if !p.Amount.IsZero() {
+		req.Amount = p.Amount.Minor()
+		req.Currency = p.Amount.Currency
+	}
+	status, err := c.send(ctx, c.endpoint("")+"/validateOTP/", req, res)
 	if err != nil {
 		return res, err
 	}
-	client := new(http.Client)
-	request, err := http.NewRequestWithContext(ctx, "POST", EndPoints[api.Mode]+"/validateOTP/", bytes.NewReader(payload))
-	if err != nil {
-		return res, err
-	}
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
-	if err != nil {
-		return res, err
-	}
-	defer response.Body.Close()
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	decoder.Decode(&res.OTP)
-	if code, err := strconv.Atoi(res.OTP.Header.ResponseCode); err == nil && code == 0 {
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
 		return res, nil
 	}
-	return res, errors.New(res.OTP.Header.ResponseDescription)
+	c.stats().OTPFailure()
+	return res, newError("ValidateOTP", status, res.Header)
 }
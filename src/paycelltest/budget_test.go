@@ -0,0 +1,41 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestDeadlineBudgetToken(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithDeadlineBudget(2*time.Second, 50*time.Millisecond))
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "getCardTokenSecure"}, Outcome: paycelltest.Outcome{Delay: 300 * time.Millisecond}, Times: 1})
+	start := time.Now()
+	_, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Auth error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 300*time.Millisecond {
+		t.Errorf("Auth took %v, want the token share to end the tokenization", elapsed)
+	}
+	if len(srv.Provisions()) != 0 {
+		t.Errorf("%d provisions after a tokenization timeout", len(srv.Provisions()))
+	}
+}
+
+func TestDeadlineBudgetProvision(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithDeadlineBudget(300*time.Millisecond, 250*time.Millisecond))
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "getCardTokenSecure"}, Outcome: paycelltest.Outcome{Delay: 200 * time.Millisecond}, Times: 1})
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Delay: 200 * time.Millisecond}, Times: 1})
+	start := time.Now()
+	_, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Auth error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 400*time.Millisecond {
+		t.Errorf("Auth took %v, want the provision step limited to the remaining total", elapsed)
+	}
+}
@@ -0,0 +1,70 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestCardManagement(t *testing.T) {
+	srv, client, params := setup(t)
+	srv.AddCustomer(msisdn, &paycelltest.Customer{EulaId: "EULA1", Limit: 100000, RemainingLimit: 100000})
+	ctx := context.Background()
+	list := func() []*paycell.StoredCard {
+		t.Helper()
+		res, err := client.GetCards(ctx, new(paycell.CardsRequest), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.CardList
+	}
+
+	_, err := client.RegisterCard(ctx, &paycell.RegisterCardRequest{Card: card(), EulaId: "OTHER"}, params)
+	var e *paycell.Error
+	if !errors.As(err, &e) || e.Code != "4008" {
+		t.Fatalf("RegisterCard with a wrong EULA error = %v, want code 4008", err)
+	}
+	personal, err := client.RegisterCard(ctx, &paycell.RegisterCardRequest{Card: card(), Alias: "Kişisel"}, params)
+	if err != nil {
+		t.Fatalf("RegisterCard error = %v", err)
+	}
+	work, err := client.RegisterCard(ctx, &paycell.RegisterCardRequest{Card: card(), Alias: "İş"}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards := list()
+	if len(cards) != 2 || cards[0].CardId != personal.CardId || cards[1].CardId != work.CardId {
+		t.Fatalf("GetCards = %+v, want the two registered cards", cards)
+	}
+	if !cards[0].IsDefault || cards[1].IsDefault || cards[1].Alias != "İş" || cards[1].MaskedCardNo != paycell.Text(paycell.MaskPAN(number)) {
+		t.Errorf("registered cards = %+v, %+v", cards[0], cards[1])
+	}
+
+	if _, err := client.UpdateCard(ctx, &paycell.UpdateCardRequest{CardId: work.CardId.String(), Alias: "Şirket", IsDefault: true}, params); err != nil {
+		t.Fatal(err)
+	}
+	cards = list()
+	if cards[0].IsDefault || !cards[1].IsDefault || cards[1].Alias != "Şirket" {
+		t.Errorf("updated cards = %+v, %+v", cards[0], cards[1])
+	}
+
+	if _, err := client.DeleteCard(ctx, &paycell.DeleteCardRequest{CardId: work.CardId.String()}, params); err != nil {
+		t.Fatal(err)
+	}
+	cards = list()
+	if len(cards) != 1 || cards[0].CardId != personal.CardId {
+		t.Errorf("GetCards after DeleteCard = %+v, want only %s", cards, personal.CardId)
+	}
+
+	_, err = client.UpdateCard(ctx, &paycell.UpdateCardRequest{CardId: work.CardId.String(), Alias: "Yok"}, params)
+	if !errors.As(err, &e) || e.Code != "4011" {
+		t.Errorf("UpdateCard of a deleted card error = %v, want code 4011", err)
+	}
+	_, err = client.DeleteCard(ctx, &paycell.DeleteCardRequest{CardId: "UNKNOWN"}, params)
+	if !errors.As(err, &e) || e.Code != "4011" {
+		t.Errorf("DeleteCard of an unknown card error = %v, want code 4011", err)
+	}
+}
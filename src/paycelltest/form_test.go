@@ -0,0 +1,35 @@
+package paycelltest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestRender3D(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	init, err := client.Auth3Dinit(ctx, &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := client.Render3D(ctx, init.Session.Form("https://shop.example/callback"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, srv.URL+"/threeDSecure") || !strings.Contains(page, init.Session.SessionId) {
+		t.Errorf("3D page does not post the session to the form endpoint:\n%s", page)
+	}
+	if strings.Contains(page, `class="spinner"`) {
+		t.Error("3D page shows a spinner by default")
+	}
+	page, err = client.Render3D(ctx, init.Session.Form("https://shop.example/callback"), &paycell.FormPage{Spinner: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, `class="spinner"`) {
+		t.Error("3D page does not show the requested spinner")
+	}
+}
@@ -0,0 +1,43 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestInquire(t *testing.T) {
+	_, client, params := setup(t)
+	ctx := context.Background()
+	sale, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inquiry, err := client.Inquire(ctx, &paycell.InquireRequest{OriginalRefNo: sale.RefNo.String()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inquiry.Status != paycell.StatusActive || inquiry.Remaining() != paycell.NewMoney(1000, "TRY") {
+		t.Errorf("inquiry status %s, remaining %v", inquiry.Status, inquiry.Remaining())
+	}
+	partial := params
+	partial.Amount = paycell.NewMoney(400, "TRY")
+	if _, err := client.Refund(ctx, &paycell.RefundRequest{OriginalRefNo: sale.RefNo.String()}, partial); err != nil {
+		t.Fatal(err)
+	}
+	inquiry, err = client.Inquire(ctx, &paycell.InquireRequest{OriginalRefNo: sale.RefNo.String()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inquiry.Status != paycell.StatusPartialRefund || inquiry.Remaining() != paycell.NewMoney(600, "TRY") {
+		t.Errorf("inquiry status %s, remaining %v", inquiry.Status, inquiry.Remaining())
+	}
+	if len(inquiry.Refunds()) != 1 || inquiry.Refunded() != paycell.NewMoney(400, "TRY") {
+		t.Errorf("inquiry refunds %v, refunded %v", inquiry.Refunds(), inquiry.Refunded())
+	}
+	if _, err := client.Inquire(ctx, &paycell.InquireRequest{OriginalRefNo: "UNKNOWN"}, params); !errors.Is(err, paycell.ErrNotFound) {
+		t.Errorf("Inquire of an unknown reference number error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestPoints(t *testing.T) {
+	srv, client, params := setup(t)
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Limit: 100000, RemainingLimit: 100000, Points: 300})
+	ctx := context.Background()
+	params, err := client.WithPoints(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.PointAmount != paycell.NewMoney(300, "TRY") {
+		t.Fatalf("WithPoints point amount = %v, want 300", params.PointAmount)
+	}
+	res, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(res.RefNo.String()); p.Amount != 1000 || p.PointAmount != 300 {
+		t.Errorf("provision amount %d, points %d", p.Amount, p.PointAmount)
+	}
+	if c, _ := srv.Customer(msisdn); c.Points != 0 {
+		t.Errorf("%d points left after the provision, want 0", c.Points)
+	}
+
+	refund := params
+	refund.Amount, refund.PointAmount = paycell.NewMoney(500, "TRY"), paycell.NewMoney(200, "TRY")
+	if _, err := client.Refund(ctx, &paycell.RefundRequest{OriginalRefNo: res.RefNo.String()}, refund); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := srv.Customer(msisdn); c.Points != 200 {
+		t.Errorf("%d points after the refund, want 200", c.Points)
+	}
+	if p, _ := srv.Provision(res.RefNo.String()); p.Refunded != 500 || p.PointRefunded != 200 {
+		t.Errorf("refunded %d, points %d", p.Refunded, p.PointRefunded)
+	}
+
+	usd := params
+	usd.Amount, usd.PointAmount = paycell.NewMoney(1000, "USD"), paycell.Money{}
+	if _, err := client.WithPoints(ctx, usd); !errors.Is(err, paycell.ErrInvalidCurrency) {
+		t.Errorf("WithPoints for a USD payment error = %v, want ErrInvalidCurrency", err)
+	}
+}
+
+func TestPointsValidation(t *testing.T) {
+	srv, client, params := setup(t)
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Limit: 100000, RemainingLimit: 100000, Points: 5000})
+	tests := []struct {
+		name   string
+		points paycell.Money
+		err    error
+	}{
+		{"above amount", paycell.NewMoney(1500, "TRY"), paycell.ErrInvalidAmount},
+		{"negative", paycell.NewMoney(-1, "TRY"), paycell.ErrInvalidAmount},
+		{"other currency", paycell.NewMoney(500, "USD"), paycell.ErrInvalidCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := params
+			p.PointAmount = tt.points
+			if _, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, p); !errors.Is(err, tt.err) {
+				t.Errorf("Auth error = %v, want %v", err, tt.err)
+			}
+			if _, err := client.Refund(context.Background(), &paycell.RefundRequest{OriginalRefNo: "REF"}, p); !errors.Is(err, tt.err) {
+				t.Errorf("Refund error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	if len(srv.Provisions()) != 0 {
+		t.Errorf("%d provisions with invalid point amounts", len(srv.Provisions()))
+	}
+}
@@ -0,0 +1,53 @@
+package paycelltest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestProvisionHistoryPaging(t *testing.T) {
+	var pages atomic.Int32
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return paycell.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Path, "/getProvisionHistory/") {
+				pages.Add(1)
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	srv, client, params := setup(t, paycell.WithMiddleware(count))
+	ctx := context.Background()
+	params.Amount = paycell.NewMoney(10, "TRY")
+	total := 2*paycell.DefaultHistoryPageSize + 1
+	var local []paycell.LedgerEntry
+	for i := 0; i < total; i++ {
+		res, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		local = append(local, paycell.LedgerEntry{RefNo: res.RefNo.String(), TransactionType: paycell.ProvisionSale, Amount: params.Amount})
+	}
+	if len(srv.Provisions()) != total {
+		t.Fatalf("%d provisions, want %d", len(srv.Provisions()), total)
+	}
+	now := time.Now()
+	remote, err := client.ProvisionHistory(ctx, now, now, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remote) != total {
+		t.Errorf("%d history records, want %d", len(remote), total)
+	}
+	if n := pages.Load(); n != 3 {
+		t.Errorf("%d history pages fetched, want 3", n)
+	}
+	if mismatches := paycell.Reconcile(local, remote); len(mismatches) != 0 {
+		t.Errorf("Reconcile = %+v, want no mismatches", mismatches)
+	}
+}
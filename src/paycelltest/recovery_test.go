@@ -0,0 +1,71 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestRecovery(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithRecovery(paycell.Recovery{Wait: 300 * time.Millisecond}))
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Delay: 150 * time.Millisecond}, Times: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatalf("recovered Auth error = %v", err)
+	}
+	if _, ok := srv.Provision(res.RefNo.String()); !ok {
+		t.Errorf("recovered provision %s not found", res.RefNo)
+	}
+}
+
+func TestRecoveryPartialRefund(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithRecovery(paycell.Recovery{Wait: 400 * time.Millisecond}))
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Delay: 150 * time.Millisecond}, Times: 1})
+	type result struct {
+		res *paycell.ProvisionResult
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		res, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+		done <- result{res, err}
+	}()
+	var provisions []paycelltest.Provision
+	for deadline := time.Now().Add(time.Second); len(provisions) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		provisions = srv.Provisions()
+	}
+	if len(provisions) != 1 {
+		t.Fatalf("%d provisions, want 1", len(provisions))
+	}
+	partial := params
+	partial.Amount = paycell.NewMoney(400, "TRY")
+	if _, err := client.Refund(context.Background(), &paycell.RefundRequest{OriginalRefNo: provisions[0].RefNo}, partial); err != nil {
+		t.Fatal(err)
+	}
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("recovered Auth of a partially refunded provision error = %v", r.err)
+	}
+	if r.res.RefNo.String() != provisions[0].RefNo {
+		t.Errorf("recovered reference number %s, want %s", r.res.RefNo, provisions[0].RefNo)
+	}
+}
+
+func TestRecoveryNotFound(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithRecovery(paycell.Recovery{Reverse: true, Wait: 10 * time.Millisecond}))
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Reset: true}, Times: 1})
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "reverse"}, Outcome: paycelltest.Outcome{Code: "9999"}})
+	_, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params)
+	if !errors.Is(err, paycell.ErrNotProcessed) {
+		t.Errorf("Auth error = %v, want ErrNotProcessed without a reverse", err)
+	}
+}
@@ -0,0 +1,124 @@
+package paycelltest
+
+import (
+	"net/http"
+	"time"
+)
+
+// Match selects the requests a scenario applies to. Empty fields match
+// everything. Operation is the endpoint name, e.g. "provision",
+// "getCardTokenSecure", "getThreeDSessionResult" or "validateOTP".
+type Match struct {
+	Operation  string
+	CardNumber string
+	MSisdn     string
+	Amount     int64
+}
+
+type Outcome struct {
+	Code        string
+	Description string
+	InvalidHash bool
+	MdStatus    string
+	Delay       time.Duration
+	Reset       bool
+	Malformed   bool
+}
+
+type Scenario struct {
+	Match
+	Outcome
+	Times int
+}
+
+type facts struct {
+	card    string
+	token   string
+	session string
+	msisdn  string
+	amount  int64
+}
+
+func (s *Server) Script(scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios = append(s.scenarios, &scenario)
+}
+
+func (s *Server) ClearScripts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios = nil
+}
+
+func (s *Server) outcome(operation string, f facts) *Outcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.session != "" {
+		if sess, ok := s.sessions[f.session]; ok {
+			f.token = sess.CardToken
+			if f.msisdn == "" {
+				f.msisdn = sess.MSisdn
+			}
+			if f.amount == 0 {
+				f.amount = sess.Amount
+			}
+		}
+	}
+	if f.card == "" && f.token != "" {
+		if card, ok := s.tokens[f.token]; ok {
+			f.card = card.Number
+		}
+	}
+	for i, sc := range s.scenarios {
+		if sc.Operation != "" && sc.Operation != operation {
+			continue
+		}
+		if sc.CardNumber != "" && sc.CardNumber != f.card {
+			continue
+		}
+		if sc.MSisdn != "" && sc.MSisdn != f.msisdn {
+			continue
+		}
+		if sc.Amount != 0 && sc.Amount != f.amount {
+			continue
+		}
+		if sc.Times > 0 {
+			sc.Times--
+			if sc.Times == 0 {
+				s.scenarios = append(s.scenarios[:i:i], s.scenarios[i+1:]...)
+			}
+		}
+		outcome := sc.Outcome
+		return &outcome
+	}
+	return nil
+}
+
+// script applies the transport level parts of a matching scenario. It
+// reports done when the response has already been written or the
+// connection dropped.
+func (s *Server) script(w http.ResponseWriter, operation string, f facts) (o *Outcome, done bool) {
+	o = s.outcome(operation, f)
+	if o == nil {
+		return nil, false
+	}
+	if o.Delay > 0 {
+		time.Sleep(o.Delay)
+	}
+	if o.Reset {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return o, true
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if o.Malformed {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"responseHeader":{"responseCode":`))
+		return o, true
+	}
+	return o, false
+}
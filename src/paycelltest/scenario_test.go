@@ -0,0 +1,94 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestScenarios(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario paycelltest.Scenario
+		timeout  time.Duration
+		check    func(err error) bool
+	}{
+		{
+			name:     "declined",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Code: "2003", Description: "Yetersiz bakiye"}, Times: 1},
+			check:    func(err error) bool { return errors.Is(err, paycell.ErrInsufficientFunds) },
+		},
+		{
+			name:     "card number",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision", CardNumber: number}, Outcome: paycelltest.Outcome{Code: "2001"}, Times: 1},
+			check:    func(err error) bool { return errors.Is(err, paycell.ErrInvalidCard) },
+		},
+		{
+			name:     "invalid hash",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "getCardTokenSecure"}, Outcome: paycelltest.Outcome{InvalidHash: true}, Times: 1},
+			check:    func(err error) bool { return errors.Is(err, paycell.ErrHashMismatch) },
+		},
+		{
+			name:     "delay",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Delay: 200 * time.Millisecond}, Times: 1},
+			timeout:  50 * time.Millisecond,
+			check:    func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+		},
+		{
+			name:     "reset",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Reset: true}, Times: 1},
+			check: func(err error) bool {
+				var e *paycell.Error
+				return err != nil && !errors.As(err, &e)
+			},
+		},
+		{
+			name:     "malformed",
+			scenario: paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Malformed: true}, Times: 1},
+			check:    func(err error) bool { return errors.Is(err, paycell.ErrInvalidResponse) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client, params := setup(t)
+			srv.Script(tt.scenario)
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+			if !tt.check(err) {
+				t.Fatalf("Auth error = %v", err)
+			}
+			if _, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params); err != nil {
+				t.Errorf("Auth after a one-time scenario: %v", err)
+			}
+		})
+	}
+}
+
+func TestScenarioThreeDFailure(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "getThreeDSessionResult"}, Outcome: paycelltest.Outcome{MdStatus: "0"}})
+	init, err := client.PreAuth3Dinit(ctx, &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate(t, srv, init.Session)
+	if _, err := client.CompletePreAuth3D(ctx, init.Session); !errors.Is(err, paycell.ErrThreeDSecure) {
+		t.Errorf("CompletePreAuth3D error = %v, want ErrThreeDSecure", err)
+	}
+	if _, err := client.PreAuth3D(ctx, &paycell.ThreeDResultRequest{ThreeDSession: init.Session.SessionId}, params); !errors.Is(err, paycell.ErrThreeDSecure) {
+		t.Errorf("PreAuth3D error = %v, want ErrThreeDSecure", err)
+	}
+	if len(srv.Provisions()) != 0 {
+		t.Errorf("%d provisions after a failed 3D authentication", len(srv.Provisions()))
+	}
+}
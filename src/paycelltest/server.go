@@ -0,0 +1,1323 @@
+// Package paycelltest provides an in-memory Paycell server for offline testing.
+package paycelltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+type Card struct {
+	CardId            string
+	Number            string
+	Month             string
+	Year              string
+	Code              string
+	Brand             paycell.CardBrand
+	Type              paycell.CardType
+	Alias             string
+	IsDefault         bool
+	IsThreeDValidated bool
+	IsOTPValidated    bool
+}
+
+type Customer struct {
+	Cards          []*Card
+	EulaId         string
+	Limit          int64
+	RemainingLimit int64
+	IsDcbOpen      bool
+	IsEulaExpired  bool
+	Points         int64
+}
+
+type Provision struct {
+	RefNo         string
+	OrderId       string
+	PaymentType   string
+	MSisdn        string
+	CardToken     string
+	CardId        string
+	Amount        int64
+	Currency      string
+	Installment   string
+	Refunded      int64
+	PointAmount   int64
+	PointRefunded int64
+	Reversed      bool
+	Date          time.Time
+	History       []paycell.ProvisionRecord
+}
+
+type Session struct {
+	Id            string
+	CardToken     string
+	MSisdn        string
+	Amount        int64
+	Currency      string
+	Transaction   string
+	Authenticated bool
+}
+
+type OTP struct {
+	Token      string
+	Code       string
+	MSisdn     string
+	RetryCount int
+	ExpireDate time.Time
+	Validated  bool
+}
+
+type Server struct {
+	*httptest.Server
+	Merchant   string
+	Name       string
+	Password   string
+	Key        string
+	OTPCode    string
+	mu         sync.Mutex
+	sequence   int
+	tokens     map[string]*Card
+	customers  map[string]*Customer
+	provisions map[string]*Provision
+	sessions   map[string]*Session
+	otps       map[string]*OTP
+	bins       map[string]*paycell.CardBin
+	scenarios  []*Scenario
+}
+
+func NewServer(merchant, password, name, key string) *Server {
+	s := &Server{
+		Merchant:   merchant,
+		Name:       name,
+		Password:   password,
+		Key:        key,
+		OTPCode:    "123456",
+		tokens:     make(map[string]*Card),
+		customers:  make(map[string]*Customer),
+		provisions: make(map[string]*Provision),
+		sessions:   make(map[string]*Session),
+		otps:       make(map[string]*OTP),
+		bins:       make(map[string]*paycell.CardBin),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/getCardTokenSecure", s.cardToken)
+	mux.HandleFunc("/threeDSecure", s.threeDForm)
+	mux.HandleFunc("/provision/", s.provision)
+	mux.HandleFunc("/refund/", s.refund)
+	mux.HandleFunc("/reverse/", s.reverse)
+	mux.HandleFunc("/inquire/", s.inquire)
+	mux.HandleFunc("/summaryReconciliation/", s.summaryReconciliation)
+	mux.HandleFunc("/getProvisionHistory/", s.provisionHistory)
+	mux.HandleFunc("/getThreeDSession/", s.threeDSession)
+	mux.HandleFunc("/getThreeDSessionResult/", s.threeDResult)
+	mux.HandleFunc("/getPaymentMethods/", s.paymentMethods)
+	mux.HandleFunc("/getCardBinInformation/", s.cardBin)
+	mux.HandleFunc("/getPointBalance/", s.pointBalance)
+	mux.HandleFunc("/getCards/", s.cards)
+	mux.HandleFunc("/registerCard/", s.registerCard)
+	mux.HandleFunc("/updateCard/", s.updateCard)
+	mux.HandleFunc("/deleteCard/", s.deleteCard)
+	mux.HandleFunc("/openMobilePayment/", s.mobilePayment)
+	mux.HandleFunc("/sendOTP/", s.sendOTP)
+	mux.HandleFunc("/validateOTP/", s.validateOTP)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) Configure(api *paycell.API) {
+	api.SetEndPoints(s.URL, s.URL+"/getCardTokenSecure", s.URL+"/threeDSecure")
+}
+
+func (s *Server) Option() paycell.Option {
+	return paycell.WithEndPoints(s.URL, s.URL+"/getCardTokenSecure", s.URL+"/threeDSecure")
+}
+
+func (s *Server) AddCustomer(msisdn string, customer *Customer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, card := range customer.Cards {
+		if card.CardId == "" {
+			card.CardId = s.next("CARD")
+		}
+	}
+	s.customers[msisdn] = customer
+}
+
+func (s *Server) Customer(msisdn string) (Customer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.customers[msisdn]; ok {
+		return *c, true
+	}
+	return Customer{}, false
+}
+
+// AddBin registers BIN information. Unregistered BINs are reported as credit
+// cards of "TEST BANK" with 2 to 12 installments.
+func (s *Server) AddBin(bin paycell.CardBin) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bins[string(bin.BinValue)] = &bin
+}
+
+func (s *Server) bin(number string) *paycell.CardBin {
+	value := paycell.BIN(number)
+	if bin, ok := s.bins[value]; ok {
+		return bin
+	}
+	return &paycell.CardBin{
+		BinValue:         paycell.Text(value),
+		BankCode:         "111",
+		BankName:         "TEST BANK",
+		CardBrand:        brand(number),
+		CardOrganization: paycell.Text(brand(number)),
+		CardType:         paycell.CardTypeCredit,
+		CommercialType:   "INDIVIDUAL",
+		Installments:     []paycell.Int{2, 3, 6, 9, 12},
+	}
+}
+
+func (s *Server) Provision(refNo string) (Provision, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.provisions[refNo]; ok {
+		return *p, true
+	}
+	return Provision{}, false
+}
+
+func (s *Server) Provisions() []Provision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Provision, 0, len(s.provisions))
+	for _, p := range s.provisions {
+		list = append(list, *p)
+	}
+	return list
+}
+
+func (s *Server) next(prefix string) string {
+	s.sequence++
+	return fmt.Sprintf("%s%012d", prefix, s.sequence)
+}
+
+func (s *Server) customer(msisdn string) *Customer {
+	c, ok := s.customers[msisdn]
+	if !ok {
+		c = &Customer{Limit: 100000, RemainingLimit: 100000}
+		s.customers[msisdn] = c
+	}
+	return c
+}
+
+func (s *Server) hash(transactionId, datetime, code, token string) string {
+	return paycell.SHA256(strings.ToUpper(s.Name + transactionId + datetime + code + token + s.Key + paycell.SHA256(strings.ToUpper(s.Password+s.Name))))
+}
+
+func (s *Server) authorized(h paycell.RequestHeader) bool {
+	return h.ApplicationName == s.Name && h.ApplicationPwd == s.Password
+}
+
+func header(req paycell.RequestHeader, code, description string) *paycell.ResponseHeader {
+	return &paycell.ResponseHeader{
+		ResponseCode:        code,
+		ResponseDescription: description,
+		ResponseDateTime:    strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", ""),
+		TransactionId:       req.TransactionId,
+	}
+}
+
+func success(req paycell.RequestHeader) *paycell.ResponseHeader {
+	return header(req, "0", "Success")
+}
+
+func decode(r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	return decoder.Decode(v) == nil
+}
+
+func encode(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func text(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func amount(v any) int64 {
+	n, _ := strconv.ParseInt(text(v), 10, 64)
+	return n
+}
+
+func (s *Server) cardToken(w http.ResponseWriter, r *http.Request) {
+	var req paycell.CardTokenRequest
+	var res paycell.CardTokenResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getCardTokenSecure", facts{card: text(req.CardNumber)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	expected := paycell.SHA256(strings.ToUpper(s.Name + h.TransactionId + h.TransactionDateTime + s.Key + paycell.SHA256(strings.ToUpper(s.Password+s.Name))))
+	if h.ApplicationName != s.Name || text(req.Hash) != expected {
+		res.Header = header(h, "1", "Hash doğrulanamadı")
+		encode(w, res)
+		return
+	}
+	if text(req.CardNumber) == "" && text(req.CardCode) == "" {
+		res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	token := s.next("TOKEN")
+	s.tokens[token] = &Card{
+		Number: text(req.CardNumber),
+		Month:  text(req.CardMonth),
+		Year:   text(req.CardYear),
+		Code:   text(req.CardCode),
+	}
+	s.mu.Unlock()
+	res.Header = success(h)
+	res.Token = token
+	res.Hash = s.hash(h.TransactionId, res.Header.ResponseDateTime, res.Header.ResponseCode, token)
+	if o != nil && o.InvalidHash {
+		res.Hash = paycell.SHA256(res.Hash)
+	}
+	encode(w, res)
+}
+
+func (s *Server) provision(w http.ResponseWriter, r *http.Request) {
+	var req paycell.ProvisionRequest
+	var res paycell.ProvisionResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "provision", facts{token: text(req.CardToken), msisdn: text(req.MSisdn), amount: amount(req.Amount)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	refNo := text(req.RefNo)
+	if _, ok := s.provisions[refNo]; ok || refNo == "" {
+		res.Header = header(h, "4001", "Referans numarası geçersiz")
+		encode(w, res)
+		return
+	}
+	p := &Provision{
+		RefNo:       refNo,
+		OrderId:     s.next("ORDER"),
+		PaymentType: text(req.PaymentType),
+		MSisdn:      text(req.MSisdn),
+		CardToken:   text(req.CardToken),
+		CardId:      text(req.CardId),
+		Amount:      amount(req.Amount),
+		Currency:    text(req.Currency),
+		Installment: text(req.Installment),
+		PointAmount: amount(req.PointAmount),
+		Date:        time.Now(),
+	}
+	switch p.PaymentType {
+	case "SALE", "PREAUTH":
+	case "POSTAUTH":
+		original, ok := s.provisions[text(req.OriginalRefNo)]
+		if !ok || original.PaymentType != "PREAUTH" || original.Reversed {
+			res.Header = header(h, "4002", "Orijinal işlem bulunamadı")
+			encode(w, res)
+			return
+		}
+	default:
+		res.Header = header(h, "4003", "Ödeme tipi geçersiz")
+		encode(w, res)
+		return
+	}
+	if p.Amount <= 0 {
+		res.Header = header(h, "4004", "Tutar geçersiz")
+		encode(w, res)
+		return
+	}
+	if session := text(req.ThreeDSession); session != "" {
+		if sess, ok := s.sessions[session]; !ok || !sess.Authenticated {
+			res.Header = header(h, "4005", "3D doğrulaması tamamlanmadı")
+			encode(w, res)
+			return
+		}
+	}
+	if p.CardId != "" {
+		c := s.customer(p.MSisdn)
+		i := find(c, p.CardId)
+		if i < 0 {
+			res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+			encode(w, res)
+			return
+		}
+		if text(req.ThreeDSession) == "" {
+			if !c.Cards[i].IsThreeDValidated {
+				res.Header = header(h, "4012", "Kart için 3D doğrulaması gerekli")
+				encode(w, res)
+				return
+			}
+			if !c.Cards[i].IsOTPValidated && !s.consumeOTP(p.MSisdn) {
+				res.Header = header(h, "4013", "Kart için OTP doğrulaması gerekli")
+				encode(w, res)
+				return
+			}
+		}
+	}
+	if installment, _ := strconv.Atoi(p.Installment); installment > 1 {
+		if number := s.number(p); number != "" && !s.bin(number).AllowsInstallment(installment) {
+			res.Header = header(h, "4014", "Taksit sayısı geçersiz")
+			encode(w, res)
+			return
+		}
+	}
+	if p.PointAmount < 0 || p.PointAmount > p.Amount {
+		res.Header = header(h, "4004", "Tutar geçersiz")
+		encode(w, res)
+		return
+	}
+	if p.PointAmount > 0 && s.customer(p.MSisdn).Points < p.PointAmount {
+		res.Header = header(h, "2003", "Yetersiz puan")
+		encode(w, res)
+		return
+	}
+	if p.CardToken == "" && p.CardId == "" && p.PaymentType != "POSTAUTH" {
+		c := s.customer(p.MSisdn)
+		if !c.IsDcbOpen || c.RemainingLimit < p.Amount-p.PointAmount {
+			res.Header = header(h, "2003", "Yetersiz limit")
+			encode(w, res)
+			return
+		}
+		c.RemainingLimit -= p.Amount - p.PointAmount
+	} else if p.CardToken != "" {
+		if _, ok := s.tokens[p.CardToken]; !ok {
+			res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+			encode(w, res)
+			return
+		}
+	}
+	s.customer(p.MSisdn).Points -= p.PointAmount
+	record := s.record(h, p.PaymentType, refNo, p.Amount, p.PointAmount, p.Currency)
+	p.History = append(p.History, record)
+	s.provisions[refNo] = p
+	res.Header = success(h)
+	res.OrderId = paycell.Text(p.OrderId)
+	res.OrderDate = paycell.Date{Time: p.Date}
+	res.ApprovalCode = record.ApprovalCode
+	res.AcquirerBank = "111"
+	res.IssuerBank = "111"
+	encode(w, res)
+}
+
+func (s *Server) refund(w http.ResponseWriter, r *http.Request) {
+	var req paycell.RefundRequest
+	var res paycell.RefundResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "refund", facts{msisdn: text(req.MSisdn), amount: amount(req.Amount)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	p, ok := s.provisions[text(req.OriginalRefNo)]
+	if !ok || p.Reversed {
+		res.Header = header(h, "4002", "Orijinal işlem bulunamadı")
+		encode(w, res)
+		return
+	}
+	total, points := amount(req.Amount), amount(req.PointAmount)
+	if total <= 0 || p.Refunded+total > p.Amount || points < 0 || points > total || p.PointRefunded+points > p.PointAmount {
+		res.Header = header(h, "4006", "İade tutarı geçersiz")
+		encode(w, res)
+		return
+	}
+	p.Refunded += total
+	p.PointRefunded += points
+	s.customer(p.MSisdn).Points += points
+	record := s.record(h, paycell.ProvisionRefund, text(req.RefNo), total, points, p.Currency)
+	p.History = append(p.History, record)
+	res.Header = success(h)
+	res.OrderId = paycell.Text(p.OrderId)
+	res.OrderDate = record.ReconciliationDate
+	res.ApprovalCode = record.ApprovalCode
+	encode(w, res)
+}
+
+func (s *Server) reverse(w http.ResponseWriter, r *http.Request) {
+	var req paycell.CancelRequest
+	var res paycell.CancelResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "reverse", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	p, ok := s.provisions[text(req.OriginalRefNo)]
+	if !ok || p.Reversed || p.Refunded > 0 {
+		res.Header = header(h, "4002", "Orijinal işlem bulunamadı")
+		encode(w, res)
+		return
+	}
+	p.Reversed = true
+	if p.CardToken == "" && p.CardId == "" {
+		s.customer(p.MSisdn).RemainingLimit += p.Amount - p.PointAmount
+	}
+	s.customer(p.MSisdn).Points += p.PointAmount
+	record := s.record(h, paycell.ProvisionReverse, text(req.RefNo), p.Amount, p.PointAmount, p.Currency)
+	p.History = append(p.History, record)
+	res.Header = success(h)
+	res.OrderId = paycell.Text(p.OrderId)
+	res.OrderDate = record.ReconciliationDate
+	res.ApprovalCode = record.ApprovalCode
+	encode(w, res)
+}
+
+func (s *Server) inquire(w http.ResponseWriter, r *http.Request) {
+	var req paycell.InquireRequest
+	var res paycell.InquireResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "inquire", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	p, ok := s.provisions[text(req.OriginalRefNo)]
+	if !ok {
+		res.Header = header(h, "4002", "Orijinal işlem bulunamadı")
+		encode(w, res)
+		return
+	}
+	res.Header = success(h)
+	res.OrderId = paycell.Text(p.OrderId)
+	res.AcquirerBank = "111"
+	res.Status = paycell.Text(status(p))
+	res.Amount = paycell.NewMoney(p.Amount, p.Currency)
+	res.PointAmount = paycell.NewMoney(p.PointAmount, p.Currency)
+	res.Currency = paycell.Text(p.Currency)
+	res.ReconciliationDate = paycell.Date{Time: p.Date}
+	for i := range p.History {
+		record := p.History[i]
+		res.Provisions = append(res.Provisions, &record)
+	}
+	encode(w, res)
+}
+
+func (s *Server) summaryReconciliation(w http.ResponseWriter, r *http.Request) {
+	var req paycell.SummaryReconciliationRequest
+	var res paycell.SummaryReconciliationResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "summaryReconciliation", facts{})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	var entries []paycell.LedgerEntry
+	for _, record := range s.history(text(req.ReconciliationDate)) {
+		entries = append(entries, paycell.LedgerEntry{TransactionType: string(record.TransactionType), Amount: record.Amount})
+	}
+	t := paycell.Totals(entries)
+	res.Header = success(h)
+	res.ReconciliationDate = paycell.Date{Time: time.Now()}
+	res.ReconciliationTotals = t
+	res.ReconciliationResult = paycell.ReconciliationOK
+	expected := []string{
+		t.SaleAmount.Minor(), strconv.Itoa(int(t.SaleCount)),
+		t.RefundAmount.Minor(), strconv.Itoa(int(t.RefundCount)),
+		t.ReverseAmount.Minor(), strconv.Itoa(int(t.ReverseCount)),
+		t.PreAuthAmount.Minor(), strconv.Itoa(int(t.PreAuthCount)),
+		t.PostAuthAmount.Minor(), strconv.Itoa(int(t.PostAuthCount)),
+	}
+	given := []any{
+		req.TotalSaleAmount, req.TotalSaleCount,
+		req.TotalRefundAmount, req.TotalRefundCount,
+		req.TotalReverseAmount, req.TotalReverseCount,
+		req.TotalPreAuthAmount, req.TotalPreAuthCount,
+		req.TotalPostAuthAmount, req.TotalPostAuthCount,
+	}
+	for i := range expected {
+		if amount(given[i]) != amount(expected[i]) {
+			res.ReconciliationResult = paycell.ReconciliationNOK
+		}
+	}
+	encode(w, res)
+}
+
+func (s *Server) provisionHistory(w http.ResponseWriter, r *http.Request) {
+	var req paycell.ProvisionHistoryRequest
+	var res paycell.ProvisionHistoryResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getProvisionHistory", facts{})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	list := s.history(text(req.ReconciliationDate))
+	index, size := int(amount(req.Index)), int(amount(req.PageSize))
+	if size <= 0 {
+		size = paycell.DefaultHistoryPageSize
+	}
+	if index < 0 || index > len(list) {
+		index = len(list)
+	}
+	end := index + size
+	if end >= len(list) {
+		end = len(list)
+	} else {
+		res.NextIndex = paycell.Int(end)
+	}
+	res.Header = success(h)
+	res.Transactions = list[index:end]
+	encode(w, res)
+}
+
+// history lists the transactions of a reconciliation day (yyyyMMdd) in
+// chronological order.
+func (s *Server) history(date string) []*paycell.HistoryRecord {
+	var list []*paycell.HistoryRecord
+	for _, p := range s.provisions {
+		for _, record := range p.History {
+			if record.ReconciliationDate.In(paycell.Location).Format("20060102") != date {
+				continue
+			}
+			original := ""
+			if string(record.RefNo) != p.RefNo {
+				original = p.RefNo
+			}
+			list = append(list, &paycell.HistoryRecord{
+				OrderId:            paycell.Text(p.OrderId),
+				OrderDate:          record.DateTime,
+				RefNo:              record.RefNo,
+				OriginalRefNo:      paycell.Text(original),
+				TransactionType:    record.ProvisionType,
+				MSisdn:             paycell.Text(p.MSisdn),
+				Amount:             record.Amount,
+				ApprovalCode:       record.ApprovalCode,
+				AcquirerBank:       "111",
+				ReconciliationDate: record.ReconciliationDate,
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].OrderDate.Equal(list[j].OrderDate.Time) {
+			return list[i].RefNo < list[j].RefNo
+		}
+		return list[i].OrderDate.Before(list[j].OrderDate.Time)
+	})
+	return list
+}
+
+func (s *Server) record(h paycell.RequestHeader, provisionType, refNo string, amount, points int64, currency string) paycell.ProvisionRecord {
+	now := time.Now()
+	return paycell.ProvisionRecord{
+		ProvisionType:       paycell.Text(provisionType),
+		TransactionId:       paycell.Text(h.TransactionId),
+		RefNo:               paycell.Text(refNo),
+		Amount:              paycell.NewMoney(amount, currency),
+		PointAmount:         paycell.NewMoney(points, currency),
+		ApprovalCode:        paycell.Text(fmt.Sprintf("%06d", s.sequence)),
+		DateTime:            paycell.Time{Time: now},
+		ReconciliationDate:  paycell.Date{Time: now},
+		ResponseCode:        "0",
+		ResponseDescription: "Success",
+	}
+}
+
+func status(p *Provision) string {
+	switch {
+	case p.Reversed:
+		return paycell.StatusReversed
+	case p.Refunded >= p.Amount:
+		return paycell.StatusRefunded
+	case p.Refunded > 0:
+		return paycell.StatusPartialRefund
+	}
+	return paycell.StatusActive
+}
+
+func (s *Server) threeDSession(w http.ResponseWriter, r *http.Request) {
+	var req paycell.ThreeDSessionRequest
+	var res paycell.ThreeDSessionResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getThreeDSession", facts{token: text(req.CardToken), msisdn: text(req.MSisdn), amount: amount(req.Amount)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	token := text(req.CardToken)
+	if cardId := text(req.CardId); cardId != "" {
+		if find(s.customer(text(req.MSisdn)), cardId) < 0 {
+			res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+			encode(w, res)
+			return
+		}
+	} else if _, ok := s.tokens[token]; !ok {
+		res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+		encode(w, res)
+		return
+	}
+	sess := &Session{
+		Id:          s.next("3DS"),
+		CardToken:   token,
+		MSisdn:      text(req.MSisdn),
+		Amount:      amount(req.Amount),
+		Currency:    text(req.Currency),
+		Transaction: text(req.Transaction),
+	}
+	s.sessions[sess.Id] = sess
+	res.Header = success(h)
+	res.ThreeDSession = paycell.Text(sess.Id)
+	encode(w, res)
+}
+
+func (s *Server) threeDForm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	id := r.PostForm.Get("threeDSessionId")
+	callback := r.PostForm.Get("callbackurl")
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if ok {
+		sess.Authenticated = true
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html><html><body onload="document.forms[0].submit()"><form action="%s" method="post"><input type="hidden" name="threeDSessionId" value="%s"></form></body></html>`, html.EscapeString(callback), html.EscapeString(id))
+}
+
+func (s *Server) threeDResult(w http.ResponseWriter, r *http.Request) {
+	var req paycell.ThreeDResultRequest
+	var res paycell.ThreeDResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getThreeDSessionResult", facts{session: text(req.ThreeDSession), msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	sess, ok := s.sessions[text(req.ThreeDSession)]
+	if !ok {
+		res.Header = header(h, "4007", "3D oturumu bulunamadı")
+		encode(w, res)
+		return
+	}
+	res.Header = success(h)
+	res.CurrentStep = "3DResult"
+	if o != nil && o.MdStatus != "" {
+		res.MdStatus = paycell.Text(o.MdStatus)
+		res.MdErrorMessage = "Authentication failed"
+		res.Operation.Result = "1"
+		res.Operation.Description = "3D doğrulaması başarısız"
+	} else if sess.Authenticated {
+		res.MdStatus = "1"
+		res.Operation.Result = "0"
+		res.Operation.Description = "Success"
+	} else {
+		res.MdStatus = "0"
+		res.MdErrorMessage = "Not authenticated"
+		res.Operation.Result = "1"
+		res.Operation.Description = "3D doğrulaması tamamlanmadı"
+	}
+	encode(w, res)
+}
+
+func (s *Server) paymentMethods(w http.ResponseWriter, r *http.Request) {
+	var req paycell.PaymentMethodsRequest
+	var res paycell.PaymentMethods
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getPaymentMethods", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	res.Header = success(h)
+	res.EulaID = paycell.Text(c.EulaId)
+	for _, card := range c.Cards {
+		res.CardList = append(res.CardList, stored(card))
+	}
+	res.MobilePayment = &paycell.MobilePaymentInfo{
+		EulaId:         paycell.Text(c.EulaId),
+		Limit:          paycell.NewMoney(c.Limit, "TRY"),
+		MaxLimit:       paycell.NewMoney(c.Limit, "TRY"),
+		RemainingLimit: paycell.NewMoney(c.RemainingLimit, "TRY"),
+		IsDcbOpen:      c.IsDcbOpen,
+		IsEulaExpired:  c.IsEulaExpired,
+	}
+	encode(w, res)
+}
+
+func (s *Server) pointBalance(w http.ResponseWriter, r *http.Request) {
+	var req paycell.PointBalanceRequest
+	var res paycell.PointBalanceResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getPointBalance", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	res.Header = success(h)
+	res.PointBalance = paycell.NewMoney(s.customer(text(req.MSisdn)).Points, "TRY")
+	encode(w, res)
+}
+
+func (s *Server) cardBin(w http.ResponseWriter, r *http.Request) {
+	var req paycell.CardBinRequest
+	var res paycell.CardBinResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getCardBinInformation", facts{card: text(req.BinValue)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	if len(text(req.BinValue)) < 6 {
+		res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+		encode(w, res)
+		return
+	}
+	res.Header = success(h)
+	res.Bins = []*paycell.CardBin{s.bin(text(req.BinValue))}
+	encode(w, res)
+}
+
+func (s *Server) cards(w http.ResponseWriter, r *http.Request) {
+	var req paycell.CardsRequest
+	var res paycell.CardsResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "getCards", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	res.Header = success(h)
+	res.EulaID = paycell.Text(c.EulaId)
+	for _, card := range c.Cards {
+		res.CardList = append(res.CardList, stored(card))
+	}
+	encode(w, res)
+}
+
+func (s *Server) registerCard(w http.ResponseWriter, r *http.Request) {
+	var req paycell.RegisterCardRequest
+	var res paycell.RegisterCardResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "registerCard", facts{token: text(req.CardToken), msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	token, ok := s.tokens[text(req.CardToken)]
+	if !ok || token.Number == "" {
+		res.Header = header(h, "2001", "Kart bilgisi geçersiz")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	if c.EulaId != "" && text(req.EulaId) != c.EulaId {
+		res.Header = header(h, "4008", "Sözleşme onayı gerekli")
+		encode(w, res)
+		return
+	}
+	card := &Card{
+		CardId:            s.next("CARD"),
+		Number:            token.Number,
+		Month:             token.Month,
+		Year:              token.Year,
+		Brand:             brand(token.Number),
+		Type:              paycell.CardTypeCredit,
+		Alias:             text(req.Alias),
+		IsThreeDValidated: text(req.ThreeDSession) != "",
+	}
+	if text(req.IsDefault) == "true" || len(c.Cards) == 0 {
+		for _, other := range c.Cards {
+			other.IsDefault = false
+		}
+		card.IsDefault = true
+	}
+	c.Cards = append(c.Cards, card)
+	res.Header = success(h)
+	res.CardId = paycell.Text(card.CardId)
+	encode(w, res)
+}
+
+func (s *Server) updateCard(w http.ResponseWriter, r *http.Request) {
+	var req paycell.UpdateCardRequest
+	var res paycell.UpdateCardResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "updateCard", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	i := find(c, text(req.CardId))
+	if i < 0 {
+		res.Header = header(h, "4011", "Kart bulunamadı")
+		encode(w, res)
+		return
+	}
+	card := c.Cards[i]
+	if req.Alias != nil {
+		card.Alias = text(req.Alias)
+	}
+	if text(req.IsDefault) == "true" {
+		for _, other := range c.Cards {
+			other.IsDefault = false
+		}
+		card.IsDefault = true
+	}
+	if text(req.ThreeDSession) != "" {
+		card.IsThreeDValidated = true
+	}
+	res.Header = success(h)
+	encode(w, res)
+}
+
+func (s *Server) deleteCard(w http.ResponseWriter, r *http.Request) {
+	var req paycell.DeleteCardRequest
+	var res paycell.DeleteCardResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "deleteCard", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	i := find(c, text(req.CardId))
+	if i < 0 {
+		res.Header = header(h, "4011", "Kart bulunamadı")
+		encode(w, res)
+		return
+	}
+	c.Cards = append(c.Cards[:i:i], c.Cards[i+1:]...)
+	res.Header = success(h)
+	encode(w, res)
+}
+
+// number returns the card number charged by a provision, if known.
+func (s *Server) number(p *Provision) string {
+	if card, ok := s.tokens[p.CardToken]; ok && card.Number != "" {
+		return card.Number
+	}
+	c := s.customer(p.MSisdn)
+	if i := find(c, p.CardId); i >= 0 {
+		return c.Cards[i].Number
+	}
+	return ""
+}
+
+func find(c *Customer, cardId string) int {
+	for i, card := range c.Cards {
+		if card.CardId == cardId {
+			return i
+		}
+	}
+	return -1
+}
+
+func stored(card *Card) *paycell.StoredCard {
+	return &paycell.StoredCard{
+		CardBrand:         card.Brand,
+		CardId:            paycell.Text(card.CardId),
+		CardType:          card.Type,
+		MaskedCardNo:      paycell.Text(paycell.MaskPAN(card.Number)),
+		Alias:             paycell.Text(card.Alias),
+		IsDefault:         card.IsDefault,
+		IsThreeDValidated: card.IsThreeDValidated,
+		IsOTPValidated:    card.IsOTPValidated,
+	}
+}
+
+func brand(number string) paycell.CardBrand {
+	switch {
+	case strings.HasPrefix(number, "4"):
+		return paycell.CardBrandVisa
+	case strings.HasPrefix(number, "5"), strings.HasPrefix(number, "2"):
+		return paycell.CardBrandMasterCard
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return paycell.CardBrandAmex
+	case strings.HasPrefix(number, "9"), strings.HasPrefix(number, "65"):
+		return paycell.CardBrandTroy
+	}
+	return ""
+}
+
+func (s *Server) mobilePayment(w http.ResponseWriter, r *http.Request) {
+	var req paycell.MobilePaymentRequest
+	var res paycell.MobilePaymentResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "openMobilePayment", facts{msisdn: text(req.MSisdn)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	c := s.customer(text(req.MSisdn))
+	if c.IsEulaExpired && text(req.EulaID) != c.EulaId {
+		res.Header = header(h, "4008", "Sözleşme onayı gerekli")
+		encode(w, res)
+		return
+	}
+	c.IsDcbOpen = true
+	c.IsEulaExpired = false
+	res.Header = success(h)
+	encode(w, res)
+}
+
+func (s *Server) sendOTP(w http.ResponseWriter, r *http.Request) {
+	var req paycell.OTPRequest
+	var res paycell.OTPResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "sendOTP", facts{msisdn: text(req.MSisdn), amount: amount(req.Amount)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	otp := &OTP{
+		Token:      s.next("OTP"),
+		Code:       s.OTPCode,
+		MSisdn:     text(req.MSisdn),
+		RetryCount: 3,
+		ExpireDate: time.Now().Add(3 * time.Minute),
+	}
+	s.otps[otp.Token] = otp
+	res.Header = success(h)
+	res.Token = paycell.Text(otp.Token)
+	res.ExpireDate = paycell.Time{Time: otp.ExpireDate}
+	res.RetryCount = paycell.Int(otp.RetryCount)
+	encode(w, res)
+}
+
+func (s *Server) validateOTP(w http.ResponseWriter, r *http.Request) {
+	var req paycell.OTPRequest
+	var res paycell.OTPResult
+	if !decode(r, &req) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	h := req.Header
+	o, done := s.script(w, "validateOTP", facts{msisdn: text(req.MSisdn), amount: amount(req.Amount)})
+	if done {
+		return
+	}
+	if o != nil && o.Code != "" {
+		res.Header = header(h, o.Code, o.Description)
+		encode(w, res)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorized(h) {
+		res.Header = header(h, "1", "Yetkisiz erişim")
+		encode(w, res)
+		return
+	}
+	otp, ok := s.otps[text(req.Token)]
+	if !ok || otp.MSisdn != text(req.MSisdn) || otp.RetryCount <= 0 || time.Now().After(otp.ExpireDate) {
+		res.Header = header(h, "4009", "OTP geçersiz")
+		encode(w, res)
+		return
+	}
+	if otp.Code != text(req.OTP) {
+		otp.RetryCount--
+		res.Header = header(h, "4010", "OTP hatalı")
+		res.RetryCount = paycell.Int(otp.RetryCount)
+		encode(w, res)
+		return
+	}
+	otp.Validated = true
+	res.Header = success(h)
+	res.Token = paycell.Text(otp.Token)
+	res.RetryCount = paycell.Int(otp.RetryCount)
+	encode(w, res)
+}
+
+// consumeOTP uses up a validated OTP of msisdn.
+func (s *Server) consumeOTP(msisdn string) bool {
+	for token, otp := range s.otps {
+		if otp.MSisdn == msisdn && otp.Validated {
+			delete(s.otps, token)
+			return true
+		}
+	}
+	return false
+}
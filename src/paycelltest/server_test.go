@@ -0,0 +1,187 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+const (
+	merchant = "9998"
+	password = "PWD"
+	name     = "APP"
+	key      = "KEY"
+	msisdn   = "905305289290"
+	number   = "4355084355084358"
+)
+
+func setup(t *testing.T, options ...paycell.Option) (*paycelltest.Server, *paycell.Client, paycell.Params) {
+	t.Helper()
+	srv := paycelltest.NewServer(merchant, password, name, key)
+	t.Cleanup(srv.Close)
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Limit: 100000, RemainingLimit: 100000, IsDcbOpen: true})
+	options = append([]paycell.Option{paycell.WithStoreKey(key), srv.Option()}, options...)
+	client := paycell.NewClient(merchant, password, name, options...)
+	params := paycell.Params{MSISDN: msisdn, ClientIP: "127.0.0.1", Amount: paycell.NewMoney(1000, "TRY")}
+	return srv, client, params
+}
+
+func card() *paycell.CardTokenRequest {
+	return &paycell.CardTokenRequest{CardNumber: number, CardMonth: "12", CardYear: "30", CardCode: "000"}
+}
+
+// authenticate posts the 3D form the way the customer's browser would.
+func authenticate(t *testing.T, srv *paycelltest.Server, session *paycell.PendingSession) {
+	t.Helper()
+	form, err := paycell.QueryString(session.Form("https://shop.example/callback"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.PostForm(srv.URL+"/threeDSecure", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("3D form: HTTP %d", res.StatusCode)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	srv, client, params := setup(t)
+	res, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := srv.Provision(res.RefNo.String())
+	if !ok {
+		t.Fatalf("provision %s not recorded", res.RefNo)
+	}
+	if p.PaymentType != "SALE" || p.Amount != 1000 || p.Currency != "TRY" || p.MSisdn != msisdn {
+		t.Errorf("provision = %+v", p)
+	}
+}
+
+func TestPreAuthPostAuth(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	pre, err := client.PreAuth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := client.PostAuth(ctx, &paycell.ProvisionRequest{OriginalRefNo: pre.RefNo.String()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(post.RefNo.String()); p.PaymentType != "POSTAUTH" {
+		t.Errorf("post-authorization recorded as %q", p.PaymentType)
+	}
+	_, err = client.PostAuth(ctx, &paycell.ProvisionRequest{OriginalRefNo: "UNKNOWN"}, params)
+	if !errors.Is(err, paycell.ErrNotFound) {
+		t.Errorf("PostAuth of unknown pre-authorization error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestThreeD(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	init, err := client.Auth3Dinit(ctx, &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Complete3D(ctx, init.Session); !errors.Is(err, paycell.ErrThreeDSecure) {
+		t.Fatalf("Complete3D before authentication error = %v, want ErrThreeDSecure", err)
+	}
+	authenticate(t, srv, init.Session)
+	res, err := client.Complete3D(ctx, init.Session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := srv.Provision(res.RefNo.String()); !ok || p.PaymentType != "SALE" || p.Amount != 1000 {
+		t.Errorf("provision = %+v, %v", p, ok)
+	}
+}
+
+func TestRefundCancel(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	sale, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := params
+	partial.Amount = paycell.NewMoney(400, "TRY")
+	if _, err := client.Refund(ctx, &paycell.RefundRequest{OriginalRefNo: sale.RefNo.String()}, partial); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(sale.RefNo.String()); p.Refunded != 400 {
+		t.Errorf("refunded %d, want 400", p.Refunded)
+	}
+	if _, err := client.Refund(ctx, &paycell.RefundRequest{OriginalRefNo: sale.RefNo.String()}, params); err == nil {
+		t.Error("refund above the remaining amount succeeded")
+	}
+
+	other, err := client.Auth(ctx, &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Cancel(ctx, &paycell.CancelRequest{OriginalRefNo: other.RefNo.String()}, params); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(other.RefNo.String()); !p.Reversed {
+		t.Error("cancelled provision not reversed")
+	}
+}
+
+func TestOTP(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	sent, err := client.SendOTP(ctx, new(paycell.OTPRequest), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong, err := client.ValidateOTP(ctx, &paycell.OTPRequest{Token: sent.Token.String(), OTP: "000000"}, params)
+	var e *paycell.Error
+	if !errors.As(err, &e) || e.Code != "4010" {
+		t.Fatalf("ValidateOTP with a wrong code error = %v, want code 4010", err)
+	}
+	if wrong.RetryCount != 2 {
+		t.Errorf("remaining retries %d, want 2", wrong.RetryCount)
+	}
+	if _, err := client.ValidateOTP(ctx, &paycell.OTPRequest{Token: sent.Token.String(), OTP: srv.OTPCode}, params); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPILegacyAmount(t *testing.T) {
+	srv, _, _ := setup(t)
+	api, req := paycell.Api(merchant, password, name)
+	api.SetStoreKey(key)
+	api.SetEndPoints(srv.URL, srv.URL+"/getCardTokenSecure", srv.URL+"/threeDSecure")
+	api.SetPhoneNumber(msisdn)
+	api.SetIPAddress("127.0.0.1")
+	api.Amount, api.Currency = "1000", "TRY"
+	req.CardToken = *card()
+	res, err := api.Auth(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(res.Provision.RefNo.String()); p.Amount != 1000 {
+		t.Errorf("provision amount %d, want 1000", p.Amount)
+	}
+
+	if err := api.SetAmount("2.50", "TRY"); err != nil {
+		t.Fatal(err)
+	}
+	if api.Amount != "250" || api.Currency != "TRY" {
+		t.Errorf("SetAmount left Amount %q, Currency %q", api.Amount, api.Currency)
+	}
+	api.Money, api.Amount = paycell.Money{}, "1.000"
+	if _, err := api.Auth(context.Background(), req); !errors.Is(err, paycell.ErrInvalidAmount) {
+		t.Errorf("Auth with a decimal legacy amount error = %v, want ErrInvalidAmount", err)
+	}
+}
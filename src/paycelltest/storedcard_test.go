@@ -0,0 +1,99 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestAuthWithCard(t *testing.T) {
+	srv, client, params := setup(t)
+	validated := &paycelltest.Card{Number: number, Month: "12", Year: "30", IsThreeDValidated: true, IsOTPValidated: true}
+	noThreeD := &paycelltest.Card{Number: number, Month: "12", Year: "30", IsOTPValidated: true}
+	noOTP := &paycelltest.Card{Number: number, Month: "12", Year: "30", IsThreeDValidated: true}
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Cards: []*paycelltest.Card{validated, noThreeD, noOTP}, Limit: 100000, RemainingLimit: 100000})
+	tests := []struct {
+		name string
+		req  *paycell.StoredCardRequest
+		err  error
+	}{
+		{name: "3D required", req: &paycell.StoredCardRequest{CardId: noThreeD.CardId}, err: paycell.ErrThreeDRequired},
+		{name: "OTP required", req: &paycell.StoredCardRequest{CardId: noOTP.CardId}, err: paycell.ErrOTPRequired},
+		{name: "CVC required", req: &paycell.StoredCardRequest{CardId: validated.CardId, RequireCVC: true}, err: paycell.ErrCVCRequired},
+		{name: "unknown card", req: &paycell.StoredCardRequest{CardId: "UNKNOWN"}, err: paycell.ErrCardNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.AuthWithCard(context.Background(), tt.req, params); !errors.Is(err, tt.err) {
+				t.Errorf("AuthWithCard error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	if len(srv.Provisions()) != 0 {
+		t.Fatalf("%d provisions after rejected stored card payments", len(srv.Provisions()))
+	}
+
+	ctx := context.Background()
+	res, err := client.AuthWithCard(ctx, &paycell.StoredCardRequest{CardId: validated.CardId}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(res.RefNo.String()); p.CardId != validated.CardId || p.CardToken != "" {
+		t.Errorf("provision = %+v, want the card id without a token", p)
+	}
+	res, err = client.AuthWithCard(ctx, &paycell.StoredCardRequest{CardId: validated.CardId, CVC: "000", RequireCVC: true}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Provision(res.RefNo.String()); p.CardId != validated.CardId || p.CardToken == "" {
+		t.Errorf("provision = %+v, want the card id with a CVC token", p)
+	}
+}
+
+func TestAuthWithCardOTP(t *testing.T) {
+	srv, client, params := setup(t)
+	noOTP := &paycelltest.Card{Number: number, Month: "12", Year: "30", IsThreeDValidated: true}
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Cards: []*paycelltest.Card{noOTP}, Limit: 100000, RemainingLimit: 100000})
+	ctx := context.Background()
+	_, err := client.AuthWithCard(ctx, &paycell.StoredCardRequest{CardId: noOTP.CardId, OTPValidated: true}, params)
+	var e *paycell.Error
+	if !errors.As(err, &e) || e.Code != "4013" {
+		t.Fatalf("AuthWithCard claiming an unvalidated OTP error = %v, want code 4013", err)
+	}
+	sent, err := client.SendOTP(ctx, new(paycell.OTPRequest), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ValidateOTP(ctx, &paycell.OTPRequest{Token: sent.Token.String(), OTP: srv.OTPCode}, params); err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.AuthWithCard(ctx, &paycell.StoredCardRequest{CardId: noOTP.CardId, OTPValidated: true}, params)
+	if err != nil {
+		t.Fatalf("AuthWithCard after ValidateOTP error = %v", err)
+	}
+	if p, ok := srv.Provision(res.RefNo.String()); !ok || p.CardId != noOTP.CardId {
+		t.Errorf("provision = %+v, %v", p, ok)
+	}
+}
+
+func TestAuth3DinitWithCard(t *testing.T) {
+	srv, client, params := setup(t)
+	noThreeD := &paycelltest.Card{Number: number, Month: "12", Year: "30"}
+	srv.AddCustomer(msisdn, &paycelltest.Customer{Cards: []*paycelltest.Card{noThreeD}, Limit: 100000, RemainingLimit: 100000})
+	ctx := context.Background()
+	init, err := client.Auth3DinitWithCard(ctx, &paycell.StoredCardRequest{CardId: noThreeD.CardId}, params)
+	if err != nil {
+		t.Fatalf("Auth3DinitWithCard for a card without 3D validation error = %v", err)
+	}
+	authenticate(t, srv, init.Session)
+	res, err := client.Complete3D(ctx, init.Session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := srv.Provision(res.RefNo.String()); !ok || p.CardId != noThreeD.CardId {
+		t.Errorf("provision = %+v, %v", p, ok)
+	}
+}
@@ -0,0 +1,127 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestThreeDHandlerDuplicateCallback(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithSessionStore(paycell.NewMemorySessionStore(), 0))
+	init, err := client.Auth3Dinit(context.Background(), &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate(t, srv, init.Session)
+	var mu sync.Mutex
+	var failures []error
+	handler := &paycell.ThreeDHandler{
+		Client: client,
+		OnFailure: func(r *http.Request, session *paycell.PendingSession, err error) string {
+			mu.Lock()
+			defer mu.Unlock()
+			failures = append(failures, err)
+			return ""
+		},
+		SuccessURL: "/ok",
+	}
+	callback := func() int {
+		body := url.Values{"threeDSessionId": {init.Session.SessionId}}.Encode()
+		r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	codes := make([]int, 4)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = callback()
+		}(i)
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusSeeOther:
+			succeeded++
+		case http.StatusConflict:
+		default:
+			t.Errorf("callback responded %d", code)
+		}
+	}
+	if succeeded != 1 || len(failures) != 0 {
+		t.Errorf("callbacks = %v, failures = %v; want one success and no failures", codes, failures)
+	}
+	if len(srv.Provisions()) != 1 {
+		t.Errorf("%d provisions, want 1", len(srv.Provisions()))
+	}
+	if code := callback(); code != http.StatusConflict {
+		t.Errorf("repeated callback responded %d, want 409", code)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/callback?threeDSessionId="+init.Session.SessionId, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET callback responded %d, want 405", w.Code)
+	}
+}
+
+func TestComplete3DSessionReleasesClaim(t *testing.T) {
+	srv, client, params := setup(t, paycell.WithSessionStore(paycell.NewMemorySessionStore(), 0))
+	ctx := context.Background()
+	init, err := client.Auth3Dinit(ctx, &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate(t, srv, init.Session)
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "getThreeDSessionResult"}, Outcome: paycelltest.Outcome{Reset: true}, Times: 1})
+	if _, err := client.Complete3DSession(ctx, init.Session.SessionId); err == nil || errors.Is(err, paycell.ErrSessionClaimed) {
+		t.Fatalf("Complete3DSession with a dropped connection error = %v", err)
+	}
+	res, err := client.Complete3DSession(ctx, init.Session.SessionId)
+	if err != nil {
+		t.Fatalf("Complete3DSession retry error = %v, want the session released", err)
+	}
+	if _, ok := srv.Provision(res.RefNo.String()); !ok {
+		t.Errorf("provision %s not recorded", res.RefNo)
+	}
+	if _, err := client.Complete3DSession(ctx, init.Session.SessionId); !errors.Is(err, paycell.ErrSessionClaimed) {
+		t.Errorf("Complete3DSession after the provision error = %v, want ErrSessionClaimed", err)
+	}
+}
+
+func TestThreeDResultHeaderFailure(t *testing.T) {
+	srv, client, params := setup(t)
+	ctx := context.Background()
+	init, err := client.PreAuth3Dinit(ctx, &paycell.ThreeDSessionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate(t, srv, init.Session)
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "getThreeDSessionResult"}, Outcome: paycelltest.Outcome{Code: "9999", Description: "Sistem hatası"}})
+	check := func(name string, err error) {
+		t.Helper()
+		var e *paycell.Error
+		if !errors.As(err, &e) || e.Code != "9999" || e.Operation != name || !errors.Is(err, paycell.ErrSystem) || errors.Is(err, paycell.ErrThreeDSecure) {
+			t.Errorf("%s error = %v, want ErrSystem with code 9999", name, err)
+		}
+	}
+	_, err = client.PreAuth3D(ctx, &paycell.ThreeDResultRequest{ThreeDSession: init.Session.SessionId}, params)
+	check("PreAuth3D", err)
+	_, err = client.Auth3D(ctx, &paycell.ThreeDResultRequest{ThreeDSession: init.Session.SessionId}, params)
+	check("Auth3D", err)
+	if len(srv.Provisions()) != 0 {
+		t.Errorf("%d provisions after a failed 3D result query", len(srv.Provisions()))
+	}
+}
@@ -0,0 +1,88 @@
+package paycelltest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+// Span is a finished span captured by a Tracer.
+type Span struct {
+	Name       string
+	Parent     *Span
+	Attributes map[string]any
+	Err        error
+	Start      time.Time
+	End        time.Time
+}
+
+// Tracer is an in-memory paycell.Tracer that records ended spans in order.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+type spanKey struct{}
+
+func NewTracer() *Tracer {
+	return new(Tracer)
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...paycell.Attribute) (context.Context, paycell.Span) {
+	s := &recorded{tracer: t, span: &Span{Name: name, Attributes: make(map[string]any), Start: time.Now()}}
+	s.span.Parent, _ = ctx.Value(spanKey{}).(*Span)
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, s.span), s
+}
+
+// Spans returns the ended spans, children before their parents.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Span(nil), t.spans...)
+}
+
+// Find returns the ended spans with the given name.
+func (t *Tracer) Find(name string) []*Span {
+	var spans []*Span
+	for _, s := range t.Spans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+type recorded struct {
+	tracer *Tracer
+	span   *Span
+}
+
+func (s *recorded) SetAttributes(attrs ...paycell.Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, attr := range attrs {
+		s.span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recorded) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.Err = err
+}
+
+func (s *recorded) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.End = time.Now()
+	s.tracer.spans = append(s.tracer.spans, s.span)
+}
@@ -0,0 +1,59 @@
+package paycelltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+	"github.com/ozgur-yalcin/paycell.go/src/paycelltest"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := paycelltest.NewTracer()
+	srv, client, params := setup(t, paycell.WithTracer(tracer))
+	res, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, token := tracer.Find("paycell.Auth"), tracer.Find("paycell.CardToken")
+	if len(auth) != 1 || len(token) != 1 {
+		t.Fatalf("spans = %v, want one paycell.Auth and one paycell.CardToken", tracer.Spans())
+	}
+	if token[0].Parent != auth[0] {
+		t.Errorf("paycell.CardToken parent = %v, want paycell.Auth", token[0].Parent)
+	}
+	if auth[0].Parent != nil {
+		t.Errorf("paycell.Auth parent = %v, want none", auth[0].Parent)
+	}
+	for key, want := range map[string]any{
+		paycell.AttrOperation:    "Auth",
+		paycell.AttrMode:         "CUSTOM",
+		paycell.AttrMerchantCode: merchant,
+		paycell.AttrResponseCode: "0",
+		paycell.AttrRefNo:        res.RefNo.String(),
+	} {
+		if got := auth[0].Attributes[key]; got != want {
+			t.Errorf("paycell.Auth %s = %v, want %v", key, got, want)
+		}
+	}
+	if _, ok := auth[0].Attributes[paycell.AttrLatency].(int64); !ok {
+		t.Errorf("paycell.Auth %s = %#v, want int64", paycell.AttrLatency, auth[0].Attributes[paycell.AttrLatency])
+	}
+	if got := token[0].Attributes[paycell.AttrResponseCode]; got != "0" {
+		t.Errorf("paycell.CardToken %s = %v, want 0", paycell.AttrResponseCode, got)
+	}
+	if _, ok := srv.Provision(res.RefNo.String()); !ok {
+		t.Errorf("provision %s not recorded", res.RefNo)
+	}
+
+	tracer.Reset()
+	srv.Script(paycelltest.Scenario{Match: paycelltest.Match{Operation: "provision"}, Outcome: paycelltest.Outcome{Code: "2003"}, Times: 1})
+	if _, err := client.Auth(context.Background(), &paycell.ProvisionRequest{Card: card()}, params); err == nil {
+		t.Fatal("declined Auth succeeded")
+	}
+	auth = tracer.Find("paycell.Auth")
+	if len(auth) != 1 || !errors.Is(auth[0].Err, paycell.ErrInsufficientFunds) || auth[0].Attributes[paycell.AttrResponseCode] != "2003" {
+		t.Errorf("declined paycell.Auth span = %+v", auth)
+	}
+}
@@ -0,0 +1,88 @@
+package paycell
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+func (p Params) validate() error {
+	if err := p.Amount.Validate(); err != nil {
+		return err
+	}
+	return p.validatePoints()
+}
+
+func (p Params) validatePoints() error {
+	if p.PointAmount.IsZero() {
+		return nil
+	}
+	if p.PointAmount.Currency != p.Amount.Currency {
+		return fmt.Errorf("%w: points in %s for %s", ErrInvalidCurrency, p.PointAmount.Currency, p.Amount.Currency)
+	}
+	if p.PointAmount.Amount < 0 || p.PointAmount.Amount > p.Amount.Amount {
+		return fmt.Errorf("%w: %s points for %s", ErrInvalidAmount, p.PointAmount, p.Amount)
+	}
+	return nil
+}
+
+// SplitPoints returns how much of total can be paid with the given point
+// balance, and the remainder to be charged to the card.
+func SplitPoints(total, balance Money) (points, card Money) {
+	points = Money{Currency: total.Currency}
+	if balance.Currency == total.Currency && balance.Amount > 0 {
+		points.Amount = min(balance.Amount, total.Amount)
+	}
+	return points, Money{Amount: total.Amount - points.Amount, Currency: total.Currency}
+}
+
+func (c *Client) GetPointBalance(ctx context.Context, req *PointBalanceRequest, p Params) (res *PointBalanceResult, err error) {
+	ctx, span := c.trace(ctx, "GetPointBalance")
+	defer func() { span.end(req, res, err) }()
+	res = new(PointBalanceResult)
+	req.MSisdn = p.MSISDN
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getPointBalance/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("GetPointBalance", status, res.Header)
+}
+
+// WithPoints sets p.PointAmount to as much of p.Amount as the customer's
+// point balance covers. A balance in another currency than p.Amount is
+// reported as ErrInvalidCurrency.
+func (c *Client) WithPoints(ctx context.Context, p Params) (Params, error) {
+	balance, err := c.GetPointBalance(ctx, new(PointBalanceRequest), p)
+	if err != nil {
+		return p, err
+	}
+	if !balance.PointBalance.IsZero() && balance.PointBalance.Currency != p.Amount.Currency {
+		return p, fmt.Errorf("%w: points in %s for %s", ErrInvalidCurrency, balance.PointBalance.Currency, p.Amount.Currency)
+	}
+	p.PointAmount, _ = SplitPoints(p.Amount, balance.PointBalance)
+	return p, nil
+}
+
+// Form returns the 3D form request for the session, enabling the point
+// option when part of the payment is made with points.
+func (s *PendingSession) Form(callbackURL string) *ThreeDFormRequest {
+	form := &ThreeDFormRequest{ThreeDSession: s.SessionId, CallbackUrl: callbackURL}
+	if !s.PointAmount.IsZero() {
+		form.IsPoint = "true"
+	}
+	return form
+}
+
+func (api *API) GetPointBalance(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().GetPointBalance(ctx, &req.PointBalance, api.params())
+	res.PointBalance = *r
+	return res, err
+}
@@ -0,0 +1,30 @@
+package paycell_test
+
+import (
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestSplitPoints(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        paycell.Money
+		balance      paycell.Money
+		points, card paycell.Money
+	}{
+		{"partial", paycell.NewMoney(1000, "TRY"), paycell.NewMoney(300, "TRY"), paycell.NewMoney(300, "TRY"), paycell.NewMoney(700, "TRY")},
+		{"covered", paycell.NewMoney(1000, "TRY"), paycell.NewMoney(5000, "TRY"), paycell.NewMoney(1000, "TRY"), paycell.NewMoney(0, "TRY")},
+		{"no balance", paycell.NewMoney(1000, "TRY"), paycell.Money{}, paycell.NewMoney(0, "TRY"), paycell.NewMoney(1000, "TRY")},
+		{"negative balance", paycell.NewMoney(1000, "TRY"), paycell.NewMoney(-50, "TRY"), paycell.NewMoney(0, "TRY"), paycell.NewMoney(1000, "TRY")},
+		{"other currency", paycell.NewMoney(1000, "USD"), paycell.NewMoney(300, "TRY"), paycell.NewMoney(0, "USD"), paycell.NewMoney(1000, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, card := paycell.SplitPoints(tt.total, tt.balance)
+			if points != tt.points || card != tt.card {
+				t.Errorf("SplitPoints = %v, %v, want %v, %v", points, card, tt.points, tt.card)
+			}
+		})
+	}
+}
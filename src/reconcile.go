@@ -0,0 +1,218 @@
+package paycell
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+const (
+	ReconciliationOK  = "OK"
+	ReconciliationNOK = "NOK"
+)
+
+// Mismatch reasons reported by Reconcile.
+const (
+	MissingAtPaycell = "MISSING_AT_PAYCELL"
+	MissingLocally   = "MISSING_LOCALLY"
+	AmountMismatch   = "AMOUNT_MISMATCH"
+	TypeMismatch     = "TYPE_MISMATCH"
+)
+
+const DefaultHistoryPageSize = 100
+
+// LedgerEntry is a transaction as recorded by the merchant. RefNo is the
+// reference number of the transaction itself, not of the original sale.
+type LedgerEntry struct {
+	RefNo           string
+	TransactionType string
+	Amount          Money
+}
+
+type Mismatch struct {
+	RefNo  string
+	Reason string
+	Local  *LedgerEntry
+	Remote *HistoryRecord
+}
+
+type ReconciliationReport struct {
+	Date       time.Time
+	Local      ReconciliationTotals
+	Summary    *SummaryReconciliationResult
+	Mismatches []Mismatch
+}
+
+func (r *ReconciliationReport) OK() bool {
+	return len(r.Mismatches) == 0 && string(r.Summary.ReconciliationResult) != ReconciliationNOK
+}
+
+func (c *Client) SummaryReconciliation(ctx context.Context, req *SummaryReconciliationRequest, p Params) (res *SummaryReconciliationResult, err error) {
+	ctx, span := c.trace(ctx, "SummaryReconciliation")
+	defer func() { span.end(req, res, err) }()
+	res = new(SummaryReconciliationResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MerchantCode = c.merchant
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/summaryReconciliation/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("SummaryReconciliation", status, res.Header)
+}
+
+func (c *Client) GetProvisionHistory(ctx context.Context, req *ProvisionHistoryRequest, p Params) (res *ProvisionHistoryResult, err error) {
+	ctx, span := c.trace(ctx, "GetProvisionHistory")
+	defer func() { span.end(req, res, err) }()
+	res = new(ProvisionHistoryResult)
+	req.Header.ClientIPAddress = p.ClientIP
+	req.Header.ApplicationName = c.name
+	req.Header.ApplicationPwd = c.password
+	req.Header.TransactionDateTime = datetime()
+	req.Header.TransactionId = Random(20)
+	req.MerchantCode = c.merchant
+	status, err := c.sendIdempotent(ctx, c.endpoint("")+"/getProvisionHistory/", req, res)
+	if err != nil {
+		return res, err
+	}
+	if code, err := strconv.Atoi(res.Header.ResponseCode); err == nil && code == 0 {
+		return res, nil
+	}
+	return res, newError("GetProvisionHistory", status, res.Header)
+}
+
+// ProvisionHistory fetches every page of the provision history for each
+// reconciliation day from from to to, inclusive.
+func (c *Client) ProvisionHistory(ctx context.Context, from, to time.Time, p Params) ([]*HistoryRecord, error) {
+	var list []*HistoryRecord
+	day := time.Date(from.In(Location).Year(), from.In(Location).Month(), from.In(Location).Day(), 0, 0, 0, 0, Location)
+	for ; !day.After(to.In(Location)); day = day.AddDate(0, 0, 1) {
+		for index := 0; ; {
+			res, err := c.GetProvisionHistory(ctx, &ProvisionHistoryRequest{
+				ReconciliationDate: day.Format("20060102"),
+				Index:              index,
+				PageSize:           DefaultHistoryPageSize,
+			}, p)
+			if err != nil {
+				return list, err
+			}
+			list = append(list, res.Transactions...)
+			if res.NextIndex <= Int(index) || len(res.Transactions) == 0 {
+				break
+			}
+			index = int(res.NextIndex)
+		}
+	}
+	return list, nil
+}
+
+// Totals sums ledger entries per transaction type.
+func Totals(entries []LedgerEntry) ReconciliationTotals {
+	var t ReconciliationTotals
+	add := func(amount *Money, count *Int, m Money) {
+		if amount.Currency == "" {
+			amount.Currency = m.Currency
+		}
+		amount.Amount += m.Amount
+		*count++
+	}
+	for _, e := range entries {
+		switch e.TransactionType {
+		case ProvisionSale:
+			add(&t.SaleAmount, &t.SaleCount, e.Amount)
+		case ProvisionRefund:
+			add(&t.RefundAmount, &t.RefundCount, e.Amount)
+		case ProvisionReverse:
+			add(&t.ReverseAmount, &t.ReverseCount, e.Amount)
+		case ProvisionPreAuth:
+			add(&t.PreAuthAmount, &t.PreAuthCount, e.Amount)
+		case ProvisionPostAuth:
+			add(&t.PostAuthAmount, &t.PostAuthCount, e.Amount)
+		}
+	}
+	return t
+}
+
+// Reconcile matches local entries with Paycell's history by reference number.
+// Each remote record matches at most one local entry, so a reference number
+// that Paycell lists more often than the ledger is reported as MissingLocally
+// for every extra record.
+func Reconcile(local []LedgerEntry, remote []*HistoryRecord) []Mismatch {
+	var mismatches []Mismatch
+	records := make(map[string][]*HistoryRecord, len(remote))
+	for _, r := range remote {
+		records[string(r.RefNo)] = append(records[string(r.RefNo)], r)
+	}
+	matched := make(map[*HistoryRecord]bool, len(remote))
+	for i := range local {
+		entry := &local[i]
+		list := records[entry.RefNo]
+		if len(list) == 0 {
+			mismatches = append(mismatches, Mismatch{RefNo: entry.RefNo, Reason: MissingAtPaycell, Local: entry})
+			continue
+		}
+		r := list[0]
+		records[entry.RefNo] = list[1:]
+		matched[r] = true
+		switch {
+		case string(r.TransactionType) != entry.TransactionType:
+			mismatches = append(mismatches, Mismatch{RefNo: entry.RefNo, Reason: TypeMismatch, Local: entry, Remote: r})
+		case r.Amount.Amount != entry.Amount.Amount:
+			mismatches = append(mismatches, Mismatch{RefNo: entry.RefNo, Reason: AmountMismatch, Local: entry, Remote: r})
+		}
+	}
+	for _, r := range remote {
+		if !matched[r] {
+			mismatches = append(mismatches, Mismatch{RefNo: string(r.RefNo), Reason: MissingLocally, Remote: r})
+		}
+	}
+	return mismatches
+}
+
+// ReconcileDay sends the local totals of a day to summaryReconciliation and
+// compares the local entries with the day's provision history.
+func (c *Client) ReconcileDay(ctx context.Context, date time.Time, local []LedgerEntry, p Params) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{Date: date, Local: Totals(local)}
+	t := report.Local
+	summary, err := c.SummaryReconciliation(ctx, &SummaryReconciliationRequest{
+		ReconciliationDate:  date.In(Location).Format("20060102"),
+		TotalSaleAmount:     t.SaleAmount.Minor(),
+		TotalSaleCount:      int(t.SaleCount),
+		TotalRefundAmount:   t.RefundAmount.Minor(),
+		TotalRefundCount:    int(t.RefundCount),
+		TotalReverseAmount:  t.ReverseAmount.Minor(),
+		TotalReverseCount:   int(t.ReverseCount),
+		TotalPreAuthAmount:  t.PreAuthAmount.Minor(),
+		TotalPreAuthCount:   int(t.PreAuthCount),
+		TotalPostAuthAmount: t.PostAuthAmount.Minor(),
+		TotalPostAuthCount:  int(t.PostAuthCount),
+	}, p)
+	report.Summary = summary
+	if err != nil {
+		return report, err
+	}
+	remote, err := c.ProvisionHistory(ctx, date, date, p)
+	if err != nil {
+		return report, err
+	}
+	report.Mismatches = Reconcile(local, remote)
+	return report, nil
+}
+
+func (api *API) SummaryReconciliation(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().SummaryReconciliation(ctx, &req.Reconciliation, api.params())
+	res.Reconciliation = *r
+	return res, err
+}
+
+func (api *API) GetProvisionHistory(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().GetProvisionHistory(ctx, &req.History, api.params())
+	res.History = *r
+	return res, err
+}
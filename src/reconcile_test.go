@@ -0,0 +1,125 @@
+package paycell_test
+
+import (
+	"reflect"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func entry(refNo, transactionType string, amount int64) paycell.LedgerEntry {
+	return paycell.LedgerEntry{RefNo: refNo, TransactionType: transactionType, Amount: paycell.NewMoney(amount, "TRY")}
+}
+
+func record(refNo, transactionType string, amount int64) *paycell.HistoryRecord {
+	return &paycell.HistoryRecord{RefNo: paycell.Text(refNo), TransactionType: paycell.Text(transactionType), Amount: paycell.NewMoney(amount, "TRY")}
+}
+
+func TestReconcile(t *testing.T) {
+	type mismatch struct {
+		RefNo  string
+		Reason string
+	}
+	tests := []struct {
+		name   string
+		local  []paycell.LedgerEntry
+		remote []*paycell.HistoryRecord
+		want   []mismatch
+	}{
+		{
+			name:   "matching",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000), entry("R2", paycell.ProvisionRefund, 400)},
+			remote: []*paycell.HistoryRecord{record("R2", paycell.ProvisionRefund, 400), record("R1", paycell.ProvisionSale, 1000)},
+		},
+		{
+			name:   "missing at paycell",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000), entry("R2", paycell.ProvisionSale, 500)},
+			remote: []*paycell.HistoryRecord{record("R1", paycell.ProvisionSale, 1000)},
+			want:   []mismatch{{"R2", paycell.MissingAtPaycell}},
+		},
+		{
+			name:   "missing locally",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000)},
+			remote: []*paycell.HistoryRecord{record("R1", paycell.ProvisionSale, 1000), record("R2", paycell.ProvisionSale, 500)},
+			want:   []mismatch{{"R2", paycell.MissingLocally}},
+		},
+		{
+			name:   "amount and type",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000), entry("R2", paycell.ProvisionSale, 500)},
+			remote: []*paycell.HistoryRecord{record("R1", paycell.ProvisionSale, 900), record("R2", paycell.ProvisionPreAuth, 500)},
+			want:   []mismatch{{"R1", paycell.AmountMismatch}, {"R2", paycell.TypeMismatch}},
+		},
+		{
+			name:   "duplicate remote",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000)},
+			remote: []*paycell.HistoryRecord{record("R1", paycell.ProvisionSale, 1000), record("R1", paycell.ProvisionSale, 1000)},
+			want:   []mismatch{{"R1", paycell.MissingLocally}},
+		},
+		{
+			name:   "duplicate local",
+			local:  []paycell.LedgerEntry{entry("R1", paycell.ProvisionSale, 1000), entry("R1", paycell.ProvisionSale, 1000)},
+			remote: []*paycell.HistoryRecord{record("R1", paycell.ProvisionSale, 1000)},
+			want:   []mismatch{{"R1", paycell.MissingAtPaycell}},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []mismatch
+			for _, m := range paycell.Reconcile(tt.local, tt.remote) {
+				got = append(got, mismatch{m.RefNo, m.Reason})
+				if (m.Local == nil) != (m.Reason == paycell.MissingLocally) || (m.Remote == nil) != (m.Reason == paycell.MissingAtPaycell) {
+					t.Errorf("mismatch %+v has wrong sides", m)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reconcile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTotals(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []paycell.LedgerEntry
+		want    paycell.ReconciliationTotals
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "per type",
+			entries: []paycell.LedgerEntry{
+				entry("R1", paycell.ProvisionSale, 1000),
+				entry("R2", paycell.ProvisionSale, 250),
+				entry("R3", paycell.ProvisionRefund, 400),
+				entry("R4", paycell.ProvisionReverse, 250),
+				entry("R5", paycell.ProvisionPreAuth, 700),
+				entry("R6", paycell.ProvisionPostAuth, 700),
+				entry("R7", "UNKNOWN", 999),
+			},
+			want: paycell.ReconciliationTotals{
+				SaleAmount:     paycell.NewMoney(1250, "TRY"),
+				SaleCount:      2,
+				RefundAmount:   paycell.NewMoney(400, "TRY"),
+				RefundCount:    1,
+				ReverseAmount:  paycell.NewMoney(250, "TRY"),
+				ReverseCount:   1,
+				PreAuthAmount:  paycell.NewMoney(700, "TRY"),
+				PreAuthCount:   1,
+				PostAuthAmount: paycell.NewMoney(700, "TRY"),
+				PostAuthCount:  1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paycell.Totals(tt.entries); got != tt.want {
+				t.Errorf("Totals = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotProcessed means an ambiguous provision was confirmed not to have
+	// been charged.
+	ErrNotProcessed = errors.New("paycell: transaction not processed")
+	// ErrReversed means an ambiguous provision was charged and then reversed.
+	ErrReversed = errors.New("paycell: transaction reversed")
+	// ErrOutcomeUnknown means the outcome could not be determined; the
+	// transaction must be checked later with Inquire.
+	ErrOutcomeUnknown = errors.New("paycell: transaction outcome unknown")
+)
+
+// DefaultRecoveryWait is the settle wait used when Recovery.Wait is zero.
+const DefaultRecoveryWait = 5 * time.Second
+
+// Recovery enables resolving provisions that fail ambiguously (transport
+// errors, timeouts or 5xx responses) with an inquire by reference number.
+// A provision found active or partially refunded is returned as successful
+// unless Reverse is set, in which case it is reversed and ErrReversed is
+// returned. A provision that is not found is reported as ErrNotProcessed and
+// is never reversed, since Paycell rejects reversing an unknown reference
+// number.
+type Recovery struct {
+	Reverse bool
+	// Wait is how long to wait before inquiring so that a request still in
+	// flight at Paycell can settle; defaults to DefaultRecoveryWait. A
+	// negative Wait inquires immediately, in which case a provision that is
+	// not found is reported as ErrOutcomeUnknown rather than ErrNotProcessed.
+	Wait time.Duration
+	// Timeout bounds the inquire and reverse calls, which run detached from
+	// the caller's context since that may already be done. Defaults to 30s.
+	Timeout time.Duration
+}
+
+func WithRecovery(recovery Recovery) Option {
+	return func(c *Client) {
+		c.recovery = &recovery
+	}
+}
+
+func ambiguous(status int, err error, header *ResponseHeader) bool {
+	if err != nil {
+		return true
+	}
+	if status >= http.StatusInternalServerError {
+		return true
+	}
+	return header == nil
+}
+
+func (c *Client) recoverProvision(ctx context.Context, operation, refNo string, res *ProvisionResult, p Params, status int, cause error) (*ProvisionResult, error) {
+	timeout := c.recovery.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	unknown := func(description string) error {
+		return &Error{Operation: operation, Description: description, StatusCode: status, Category: ErrOutcomeUnknown}
+	}
+	if res.Header == nil {
+		res.Header = new(ResponseHeader)
+	}
+	wait := c.recovery.Wait
+	if wait == 0 {
+		wait = DefaultRecoveryWait
+	}
+	settled := wait > 0
+	if settled {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, unknown("recovery: " + ctx.Err().Error())
+		case <-timer.C:
+		}
+	}
+	inquiry, err := c.Inquire(ctx, &InquireRequest{OriginalRefNo: refNo}, p)
+	if errors.Is(err, ErrNotFound) {
+		if !settled {
+			return res, unknown("not found before settling")
+		}
+		return res, &Error{Operation: operation, Description: "not processed", StatusCode: status, Category: ErrNotProcessed}
+	}
+	if err != nil {
+		if cause != nil {
+			return res, unknown(cause.Error() + "; inquire: " + err.Error())
+		}
+		return res, unknown("inquire: " + err.Error())
+	}
+	switch inquiry.Status {
+	case StatusActive, StatusPartialRefund:
+	case StatusReversed, StatusRefunded:
+		return res, &Error{Operation: operation, Description: "reversed", StatusCode: status, Category: ErrReversed}
+	default:
+		return res, unknown("unexpected status " + strconv.Quote(string(inquiry.Status)))
+	}
+	if c.recovery.Reverse {
+		if _, err := c.Cancel(ctx, &CancelRequest{OriginalRefNo: refNo}, p); err != nil {
+			return res, unknown("reverse: " + err.Error())
+		}
+		return res, &Error{Operation: operation, Description: "reversed", StatusCode: status, Category: ErrReversed}
+	}
+	*res.Header = *inquiry.Header
+	res.OrderId = inquiry.OrderId
+	res.AcquirerBank = inquiry.AcquirerBank
+	res.RefNo = Text(refNo)
+	return res, nil
+}
@@ -24,7 +24,7 @@ func QueryString(v interface{}) (url.Values, error) {
 }
 
 func String(v reflect.Value) string {
-	for v.Kind() == reflect.Ptr {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		if v.IsNil() {
 			return ""
 		}
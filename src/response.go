@@ -0,0 +1,137 @@
+package paycell
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidResponse = errors.New("paycell: invalid response")
+	ErrEmptyResponse   = errors.New("paycell: empty response body")
+	ErrMissingHeader   = errors.New("paycell: response header missing")
+	ErrContentType     = errors.New("paycell: unexpected content type")
+	ErrResponseSize    = errors.New("paycell: response too large")
+	ErrStatusMismatch  = errors.New("paycell: success response code with HTTP error status")
+)
+
+// MaxResponseSize limits how much of a response body is read.
+var MaxResponseSize int64 = 1 << 20
+
+// ResponseError is returned when a Paycell response cannot be used: a non-JSON
+// content type, a body that does not decode, one without a response header or
+// a non-2xx response whose header claims success. Body holds the raw response
+// for diagnostics. Other responses carrying a Paycell response header are
+// reported by the operation as *Error whatever their HTTP status.
+type ResponseError struct {
+	Operation   string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Err         error
+}
+
+func (e *ResponseError) Error() string {
+	msg := e.Operation + ": invalid response (HTTP " + strconv.Itoa(e.StatusCode)
+	if e.ContentType != "" {
+		msg += ", " + e.ContentType
+	}
+	msg += ")"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap matches ErrInvalidResponse, ErrSystem for 5xx statuses and the
+// underlying cause.
+func (e *ResponseError) Unwrap() []error {
+	errs := []error{ErrInvalidResponse}
+	if e.StatusCode >= http.StatusInternalServerError {
+		errs = append(errs, ErrSystem)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// decode reads the response into out and validates it. A non-2xx response
+// with a Paycell header is left to the caller, which reports its code as
+// *Error; one that claims success contradicts its status and is reported here
+// as ErrStatusMismatch.
+func decode(url string, response *http.Response, out any) error {
+	e := &ResponseError{
+		Operation:   operation(url),
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, MaxResponseSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > MaxResponseSize {
+		e.Body, e.Err = body[:MaxResponseSize], ErrResponseSize
+		return e
+	}
+	e.Body = body
+	if !jsonContent(e.ContentType) {
+		e.Err = ErrContentType
+		return e
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		e.Err = ErrEmptyResponse
+		return e
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		e.Err = err
+		return e
+	}
+	header := responseHeader(out)
+	if header == nil && hasHeader(out) {
+		e.Err = ErrMissingHeader
+		return e
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		if header == nil {
+			return e
+		}
+		if code, err := strconv.Atoi(header.ResponseCode); err == nil && code == 0 {
+			e.Err = ErrStatusMismatch
+			return e
+		}
+	}
+	return nil
+}
+
+// jsonContent accepts JSON media types and a missing Content-Type.
+func jsonContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func hasHeader(out any) bool {
+	t := reflect.TypeOf(out)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := t.FieldByName("Header")
+	return ok
+}
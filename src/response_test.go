@@ -0,0 +1,81 @@
+package paycell_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestResponseStatus(t *testing.T) {
+	const (
+		success  = `{"responseHeader":{"responseCode":"0","responseDescription":"Success"}}`
+		declined = `{"responseHeader":{"responseCode":"2003","responseDescription":"Yetersiz bakiye"}}`
+		system   = `{"responseHeader":{"responseCode":"9999","responseDescription":"Sistem hatası"}}`
+		page     = `<html><body>Service Unavailable</body></html>`
+	)
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		invalid     error
+		is          []error
+		not         []error
+	}{
+		{name: "success with error status", status: 400, contentType: "application/json", body: success, invalid: paycell.ErrStatusMismatch, not: []error{paycell.ErrDeclined, paycell.ErrSystem}},
+		{name: "success with server error status", status: 502, contentType: "application/json", body: success, invalid: paycell.ErrStatusMismatch, is: []error{paycell.ErrSystem}},
+		{name: "declined with error status", status: 402, contentType: "application/json", body: declined, is: []error{paycell.ErrInsufficientFunds}, not: []error{paycell.ErrInvalidResponse}},
+		{name: "system error with server error status", status: 503, contentType: "application/json", body: system, is: []error{paycell.ErrSystem}, not: []error{paycell.ErrInvalidResponse}},
+		{name: "no header with error status", status: 502, contentType: "application/json", body: `{}`, invalid: paycell.ErrMissingHeader, is: []error{paycell.ErrSystem}},
+		{name: "html with server error status", status: 503, contentType: "text/html; charset=utf-8", body: page, invalid: paycell.ErrContentType, is: []error{paycell.ErrSystem}},
+		{name: "html with success status", status: 200, contentType: "text/html", body: page, invalid: paycell.ErrContentType, not: []error{paycell.ErrSystem}},
+		{name: "empty with success status", status: 200, contentType: "application/json", invalid: paycell.ErrEmptyResponse},
+		{name: "empty with server error status", status: 500, invalid: paycell.ErrEmptyResponse, is: []error{paycell.ErrSystem}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			client := paycell.NewClient("M1", "PWD", "APP", paycell.WithEndPoints(srv.URL, srv.URL+"/getCardTokenSecure", srv.URL+"/threeDSecure"))
+			params := paycell.Params{MSISDN: "905305289290", ClientIP: "127.0.0.1", Amount: paycell.NewMoney(1000, "TRY")}
+			_, err := client.PostAuth(context.Background(), &paycell.ProvisionRequest{OriginalRefNo: "REF"}, params)
+			if err == nil {
+				t.Fatal("PostAuth succeeded")
+			}
+			var invalid *paycell.ResponseError
+			if tt.invalid != nil {
+				if !errors.As(err, &invalid) || !errors.Is(err, paycell.ErrInvalidResponse) || !errors.Is(err, tt.invalid) {
+					t.Fatalf("PostAuth error = %v, want *ResponseError matching %v", err, tt.invalid)
+				}
+				if invalid.StatusCode != tt.status || string(invalid.Body) != tt.body {
+					t.Errorf("ResponseError status %d, body %q", invalid.StatusCode, invalid.Body)
+				}
+			} else {
+				var e *paycell.Error
+				if !errors.As(err, &e) || e.StatusCode != tt.status || e.Operation != "PostAuth" {
+					t.Fatalf("PostAuth error = %#v, want *Error with HTTP %d", err, tt.status)
+				}
+			}
+			for _, target := range tt.is {
+				if !errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = false", err, target)
+				}
+			}
+			for _, target := range tt.not {
+				if errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = true", err, target)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// RetryPolicy controls how idempotent operations (CardToken,
+// GetPaymentMethods, PreAuth3D, Auth3D, Inquire, GetCards,
+// GetCardBinInformation, GetPointBalance, SummaryReconciliation and
+// GetProvisionHistory) are retried. Provision, refund and reverse requests
+// are never retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable reports whether an attempt that ended with the given HTTP
+	// status or transport error should be retried; defaults to Retryable.
+	Retryable func(status int, err error) bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = &policy
+	}
+}
+
+// Retryable retries transport errors (including per-attempt HTTP timeouts),
+// 429 and 5xx responses and 2xx responses that could not be decoded. The
+// caller's own cancellation and deadline are checked separately and never
+// retried.
+func Retryable(status int, err error) bool {
+	var invalid *ResponseError
+	if errors.As(err, &invalid) {
+		return invalid.StatusCode < http.StatusBadRequest || invalid.StatusCode == http.StatusTooManyRequests || invalid.StatusCode >= http.StatusInternalServerError
+	}
+	var declined *Error
+	if err != nil && !errors.As(err, &declined) {
+		return true
+	}
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before the given retry (1-based): exponential
+// growth capped at MaxDelay (uncapped when MaxDelay is not positive), with
+// jitter over the upper half of the interval.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// sendIdempotent is send with the client's retry policy applied.
+func (c *Client) sendIdempotent(ctx context.Context, url string, in, out any) (status int, err error) {
+	policy := c.retry
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return c.send(ctx, url, in, out)
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	for attempt := 1; ; attempt++ {
+		status, err = c.send(ctx, url, in, out)
+		if attempt >= policy.MaxAttempts || !retryable(status, err) || ctx.Err() != nil {
+			return status, err
+		}
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return status, err
+		case <-timer.C:
+		}
+		reflect.ValueOf(out).Elem().Set(reflect.Zero(reflect.TypeOf(out).Elem()))
+	}
+}
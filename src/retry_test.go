@@ -0,0 +1,159 @@
+package paycell_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+// scripted answers each request with the next reply and repeats the last one.
+type scripted struct {
+	mu      sync.Mutex
+	replies []func() (*http.Response, error)
+	calls   int
+}
+
+func (s *scripted) Do(r *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reply := s.replies[min(s.calls, len(s.replies)-1)]
+	s.calls++
+	return reply()
+}
+
+func reply(status int, body string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+}
+
+func fail(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return nil, err
+	}
+}
+
+var errReset = errors.New("connection reset by peer")
+
+func retryClient(doer paycell.Doer) *paycell.Client {
+	return paycell.NewClient("M1", "PWD", "APP",
+		paycell.WithEndPoints("http://paycell.test", "http://paycell.test/getCardTokenSecure", "http://paycell.test/threeDSecure"),
+		paycell.WithHTTPClient(doer),
+		paycell.WithRetryPolicy(paycell.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+	)
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	const (
+		unavailable = `{"responseHeader":{"responseCode":"9999"},"eulaID":"STALE","cardList":[{"cardId":"CARD1"}]}`
+		ok          = `{"responseHeader":{"responseCode":"0","responseDescription":"Success"}}`
+	)
+	doer := &scripted{replies: []func() (*http.Response, error){fail(errReset), reply(503, unavailable), reply(200, ok)}}
+	res, err := retryClient(doer).GetPaymentMethods(context.Background(), new(paycell.PaymentMethodsRequest), paycell.Params{MSISDN: "905305289290"})
+	if err != nil {
+		t.Fatalf("GetPaymentMethods error = %v", err)
+	}
+	if doer.calls != 3 {
+		t.Errorf("%d attempts, want 3", doer.calls)
+	}
+	if res.EulaID != "" || res.CardList != nil {
+		t.Errorf("result kept fields of a failed attempt: %+v", res)
+	}
+
+	doer = &scripted{replies: []func() (*http.Response, error){reply(503, unavailable)}}
+	if _, err := retryClient(doer).GetPaymentMethods(context.Background(), new(paycell.PaymentMethodsRequest), paycell.Params{}); !errors.Is(err, paycell.ErrSystem) {
+		t.Errorf("GetPaymentMethods error = %v, want ErrSystem", err)
+	}
+	if doer.calls != 3 {
+		t.Errorf("%d attempts, want MaxAttempts", doer.calls)
+	}
+
+	doer = &scripted{replies: []func() (*http.Response, error){reply(200, `{"responseHeader":{"responseCode":"2004"}}`)}}
+	if _, err := retryClient(doer).GetPaymentMethods(context.Background(), new(paycell.PaymentMethodsRequest), paycell.Params{}); !errors.Is(err, paycell.ErrDeclined) {
+		t.Errorf("GetPaymentMethods error = %v, want ErrDeclined", err)
+	}
+	if doer.calls != 1 {
+		t.Errorf("declined response attempted %d times, want 1", doer.calls)
+	}
+}
+
+func TestRetryNeverRepeatsMoneyMovement(t *testing.T) {
+	params := paycell.Params{MSISDN: "905305289290", ClientIP: "127.0.0.1", Amount: paycell.NewMoney(1000, "TRY")}
+	operations := map[string]func(c *paycell.Client) error{
+		"provision": func(c *paycell.Client) error {
+			_, err := c.PostAuth(context.Background(), &paycell.ProvisionRequest{OriginalRefNo: "REF"}, params)
+			return err
+		},
+		"refund": func(c *paycell.Client) error {
+			_, err := c.Refund(context.Background(), &paycell.RefundRequest{OriginalRefNo: "REF"}, params)
+			return err
+		},
+		"reverse": func(c *paycell.Client) error {
+			_, err := c.Cancel(context.Background(), &paycell.CancelRequest{OriginalRefNo: "REF"}, params)
+			return err
+		},
+	}
+	replies := map[string]func() (*http.Response, error){
+		"503":       reply(503, `{"responseHeader":{"responseCode":"9999"}}`),
+		"transport": fail(errReset),
+	}
+	for name, operation := range operations {
+		for failure, r := range replies {
+			t.Run(name+" "+failure, func(t *testing.T) {
+				doer := &scripted{replies: []func() (*http.Response, error){r}}
+				if err := operation(retryClient(doer)); err == nil {
+					t.Fatal("operation succeeded")
+				}
+				if doer.calls != 1 {
+					t.Errorf("%d attempts, want 1", doer.calls)
+				}
+			})
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy paycell.RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "uncapped",
+			policy: paycell.RetryPolicy{BaseDelay: 40 * time.Millisecond},
+			want:   []time.Duration{40 * time.Millisecond, 80 * time.Millisecond, 160 * time.Millisecond, 320 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: paycell.RetryPolicy{BaseDelay: 40 * time.Millisecond, MaxDelay: 100 * time.Millisecond},
+			want:   []time.Duration{40 * time.Millisecond, 80 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name:   "no delay",
+			policy: paycell.RetryPolicy{},
+			want:   []time.Duration{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				for n := 0; n < 20; n++ {
+					if got := tt.policy.Backoff(i + 1); got < want/2 || got > want {
+						t.Fatalf("backoff(%d) = %v, want within [%v, %v]", i+1, got, want/2, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package paycell
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DefaultSessionTTL = 15 * time.Minute
+
+// SessionStore keeps pending 3D sessions between Auth3Dinit and the callback.
+// Load returns ErrSessionNotFound for unknown or expired sessions. Claim
+// atomically marks a session as being completed so that it is completed only
+// once; it returns ErrSessionClaimed when the session was already claimed. A
+// claimed session stays loadable until it expires. Release un-claims a
+// session whose completion failed before the provision was sent.
+type SessionStore interface {
+	Save(ctx context.Context, session *PendingSession, ttl time.Duration) error
+	Load(ctx context.Context, sessionId string) (*PendingSession, error)
+	Claim(ctx context.Context, sessionId string) error
+	Release(ctx context.Context, sessionId string) error
+	Delete(ctx context.Context, sessionId string) error
+}
+
+func WithSessionStore(store SessionStore, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.sessions = store
+		c.sessionTTL = ttl
+	}
+}
+
+func (c *Client) saveSession(ctx context.Context, session *PendingSession) error {
+	if c.sessions == nil {
+		return nil
+	}
+	ttl := c.sessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return c.sessions.Save(ctx, session, ttl)
+}
+
+func (c *Client) LoadSession(ctx context.Context, sessionId string) (*PendingSession, error) {
+	if c.sessions == nil {
+		return nil, ErrSessionNotFound
+	}
+	return c.sessions.Load(ctx, sessionId)
+}
+
+// Complete3DSession loads a stored session and completes it as a sale or a
+// pre-authorization depending on how it was initiated.
+func (c *Client) Complete3DSession(ctx context.Context, sessionId string) (*ProvisionResult, error) {
+	session, err := c.LoadSession(ctx, sessionId)
+	if err != nil {
+		return new(ProvisionResult), err
+	}
+	if session.Transaction == "PREAUTH" {
+		return c.CompletePreAuth3D(ctx, session)
+	}
+	return c.Complete3D(ctx, session)
+}
+
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	session PendingSession
+	expires time.Time
+	claimed bool
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+func (s *MemorySessionStore) Save(ctx context.Context, session *PendingSession, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, m := range s.sessions {
+		if now.After(m.expires) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.SessionId] = memorySession{session: *session, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemorySessionStore) Load(ctx context.Context, sessionId string) (*PendingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.sessions[sessionId]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if time.Now().After(m.expires) {
+		delete(s.sessions, sessionId)
+		return nil, ErrSessionNotFound
+	}
+	session := m.session
+	return &session, nil
+}
+
+func (s *MemorySessionStore) Claim(ctx context.Context, sessionId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.sessions[sessionId]
+	if !ok || time.Now().After(m.expires) {
+		return ErrSessionNotFound
+	}
+	if m.claimed {
+		return ErrSessionClaimed
+	}
+	m.claimed = true
+	s.sessions[sessionId] = m
+	return nil
+}
+
+func (s *MemorySessionStore) Release(ctx context.Context, sessionId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.sessions[sessionId]; ok {
+		m.claimed = false
+		s.sessions[sessionId] = m
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, sessionId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionId)
+	return nil
+}
+
+// SQLSessionStore keeps sessions in a database/sql table. Set Postgres for
+// drivers using $n placeholders; "?" is used otherwise.
+type SQLSessionStore struct {
+	DB       *sql.DB
+	Table    string
+	Postgres bool
+}
+
+func NewSQLSessionStore(db *sql.DB, table string) *SQLSessionStore {
+	return &SQLSessionStore{DB: db, Table: table}
+}
+
+func (s *SQLSessionStore) arg(n int) string {
+	if s.Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+func (s *SQLSessionStore) CreateTable(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.Table+` (
+	session_id VARCHAR(64) NOT NULL PRIMARY KEY,
+	card_id VARCHAR(64) NOT NULL,
+	card_token VARCHAR(128) NOT NULL,
+	ref_no VARCHAR(32) NOT NULL,
+	transaction_type VARCHAR(16) NOT NULL,
+	msisdn VARCHAR(16) NOT NULL,
+	client_ip VARCHAR(64) NOT NULL,
+	amount BIGINT NOT NULL,
+	point_amount BIGINT NOT NULL,
+	currency VARCHAR(3) NOT NULL,
+	installment INTEGER NOT NULL,
+	claimed INTEGER NOT NULL DEFAULT 0,
+	expires_at BIGINT NOT NULL
+)`)
+	return err
+}
+
+func (s *SQLSessionStore) Save(ctx context.Context, session *PendingSession, ttl time.Duration) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.Table+` WHERE session_id = `+s.arg(1)+` OR expires_at < `+s.arg(2), session.SessionId, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	query := `INSERT INTO ` + s.Table + ` (session_id, card_id, card_token, ref_no, transaction_type, msisdn, client_ip, amount, point_amount, currency, installment, expires_at) VALUES (` +
+		s.arg(1) + `, ` + s.arg(2) + `, ` + s.arg(3) + `, ` + s.arg(4) + `, ` + s.arg(5) + `, ` + s.arg(6) + `, ` + s.arg(7) + `, ` + s.arg(8) + `, ` + s.arg(9) + `, ` + s.arg(10) + `, ` + s.arg(11) + `, ` + s.arg(12) + `)`
+	if _, err := tx.ExecContext(ctx, query,
+		session.SessionId,
+		session.CardId,
+		session.CardToken,
+		session.RefNo,
+		session.Transaction,
+		session.MSISDN,
+		session.ClientIP,
+		session.Amount.Amount,
+		session.PointAmount.Amount,
+		session.Amount.Currency,
+		session.Installment,
+		time.Now().Add(ttl).UnixMilli(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLSessionStore) Load(ctx context.Context, sessionId string) (*PendingSession, error) {
+	session := new(PendingSession)
+	var expires int64
+	err := s.DB.QueryRowContext(ctx, `SELECT session_id, card_id, card_token, ref_no, transaction_type, msisdn, client_ip, amount, point_amount, currency, installment, expires_at FROM `+s.Table+` WHERE session_id = `+s.arg(1), sessionId).Scan(
+		&session.SessionId,
+		&session.CardId,
+		&session.CardToken,
+		&session.RefNo,
+		&session.Transaction,
+		&session.MSISDN,
+		&session.ClientIP,
+		&session.Amount.Amount,
+		&session.PointAmount.Amount,
+		&session.Amount.Currency,
+		&session.Installment,
+		&expires,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.PointAmount.Amount != 0 {
+		session.PointAmount.Currency = session.Amount.Currency
+	}
+	if time.Now().UnixMilli() > expires {
+		if err := s.Delete(ctx, sessionId); err != nil {
+			return nil, err
+		}
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// Claim uses a conditional UPDATE so that concurrent callbacks cannot both
+// claim the same session.
+func (s *SQLSessionStore) Claim(ctx context.Context, sessionId string) error {
+	result, err := s.DB.ExecContext(ctx, `UPDATE `+s.Table+` SET claimed = 1 WHERE session_id = `+s.arg(1)+` AND claimed = 0 AND expires_at >= `+s.arg(2), sessionId, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 1 {
+		return err
+	}
+	if _, err := s.Load(ctx, sessionId); err != nil {
+		return err
+	}
+	return ErrSessionClaimed
+}
+
+func (s *SQLSessionStore) Release(ctx context.Context, sessionId string) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE `+s.Table+` SET claimed = 0 WHERE session_id = `+s.arg(1), sessionId)
+	return err
+}
+
+func (s *SQLSessionStore) Delete(ctx context.Context, sessionId string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM `+s.Table+` WHERE session_id = `+s.arg(1), sessionId)
+	return err
+}
@@ -0,0 +1,254 @@
+package paycell_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+// stubDriver is a database/sql driver that understands the statements issued
+// by SQLSessionStore. A data source name starting with "postgres" requires $n
+// placeholders; any other requires "?".
+type stubDriver struct {
+	mu     sync.Mutex
+	tables map[string]map[string][]driver.Value
+}
+
+var stub = &stubDriver{tables: make(map[string]map[string][]driver.Value)}
+
+func init() {
+	sql.Register("paycellstub", stub)
+}
+
+func (d *stubDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tables[name] == nil {
+		d.tables[name] = make(map[string][]driver.Value)
+	}
+	return &stubConn{driver: d, rows: d.tables[name], postgres: strings.HasPrefix(name, "postgres")}, nil
+}
+
+type stubConn struct {
+	driver   *stubDriver
+	rows     map[string][]driver.Value
+	postgres bool
+}
+
+var (
+	dollar  = regexp.MustCompile(`\$\d+`)
+	spaces  = regexp.MustCompile(`\s+`)
+	table   = regexp.MustCompile(`^(DELETE FROM|UPDATE|SELECT .* FROM|INSERT INTO) \w+`)
+	columns = []string{"session_id", "card_id", "card_token", "ref_no", "transaction_type", "msisdn", "client_ip", "amount", "point_amount", "currency", "installment", "expires_at"}
+)
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	query = strings.TrimSpace(spaces.ReplaceAllString(query, " "))
+	if c.postgres && strings.Contains(query, "?") || !c.postgres && dollar.MatchString(query) {
+		return nil, fmt.Errorf("stub: wrong placeholder style in %q", query)
+	}
+	query = table.ReplaceAllString(dollar.ReplaceAllString(query, "?"), "$1 t")
+	return &stubStmt{conn: c, query: query}, nil
+}
+
+func (c *stubConn) Close() error              { return nil }
+func (c *stubConn) Begin() (driver.Tx, error) { return stubTx{}, nil }
+
+type stubTx struct{}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+type stubStmt struct {
+	conn  *stubConn
+	query string
+}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	rows := s.conn.rows
+	var n int64
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+	case s.query == "DELETE FROM t WHERE session_id = ? OR expires_at < ?":
+		for id, row := range rows {
+			if id == args[0] || row[11].(int64) < args[1].(int64) {
+				delete(rows, id)
+				n++
+			}
+		}
+	case s.query == "DELETE FROM t WHERE session_id = ?":
+		if _, ok := rows[args[0].(string)]; ok {
+			delete(rows, args[0].(string))
+			n++
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO t ("):
+		if len(args) != len(columns) {
+			return nil, fmt.Errorf("stub: %d values for %d columns", len(args), len(columns))
+		}
+		rows[args[0].(string)] = append(append([]driver.Value(nil), args...), int64(0))
+		n = 1
+	case s.query == "UPDATE t SET claimed = 1 WHERE session_id = ? AND claimed = 0 AND expires_at >= ?":
+		if row, ok := rows[args[0].(string)]; ok && row[12].(int64) == 0 && row[11].(int64) >= args[1].(int64) {
+			row[12] = int64(1)
+			n = 1
+		}
+	case s.query == "UPDATE t SET claimed = 0 WHERE session_id = ?":
+		if row, ok := rows[args[0].(string)]; ok {
+			row[12] = int64(0)
+			n = 1
+		}
+	default:
+		return nil, fmt.Errorf("stub: unsupported statement %q", s.query)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query != "SELECT "+strings.Join(columns, ", ")+" FROM t WHERE session_id = ?" {
+		return nil, fmt.Errorf("stub: unsupported query %q", s.query)
+	}
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	rows := &stubRows{}
+	if row, ok := s.conn.rows[args[0].(string)]; ok {
+		rows.rows = [][]driver.Value{append([]driver.Value(nil), row[:len(columns)]...)}
+	}
+	return rows, nil
+}
+
+type stubRows struct {
+	rows [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSessionStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) paycell.SessionStore{
+		"memory": func(t *testing.T) paycell.SessionStore {
+			return paycell.NewMemorySessionStore()
+		},
+		"sql": func(t *testing.T) paycell.SessionStore {
+			return openStore(t, "mysql:"+t.Name(), false)
+		},
+		"sql postgres": func(t *testing.T) paycell.SessionStore {
+			return openStore(t, "postgres:"+t.Name(), true)
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			testSessionStore(t, open(t))
+		})
+	}
+}
+
+func openStore(t *testing.T, dsn string, postgres bool) *paycell.SQLSessionStore {
+	t.Helper()
+	db, err := sql.Open("paycellstub", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := paycell.NewSQLSessionStore(db, "paycell_sessions")
+	store.Postgres = postgres
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func testSessionStore(t *testing.T, store paycell.SessionStore) {
+	ctx := context.Background()
+	session := &paycell.PendingSession{
+		SessionId:   "S1",
+		CardToken:   "TOKEN1",
+		RefNo:       "REF1",
+		Transaction: "PREAUTH",
+		Params: paycell.Params{
+			MSISDN:      "905305289290",
+			ClientIP:    "127.0.0.1",
+			Amount:      paycell.NewMoney(1000, "TRY"),
+			PointAmount: paycell.NewMoney(250, "TRY"),
+			Installment: 3,
+		},
+	}
+	if _, err := store.Load(ctx, "S1"); !errors.Is(err, paycell.ErrSessionNotFound) {
+		t.Errorf("Load of an unknown session error = %v, want ErrSessionNotFound", err)
+	}
+	if err := store.Claim(ctx, "S1"); !errors.Is(err, paycell.ErrSessionNotFound) {
+		t.Errorf("Claim of an unknown session error = %v, want ErrSessionNotFound", err)
+	}
+	if err := store.Save(ctx, session, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(ctx, "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded != *session {
+		t.Errorf("Load = %+v, want %+v", loaded, session)
+	}
+	if err := store.Claim(ctx, "S1"); err != nil {
+		t.Fatalf("Claim error = %v", err)
+	}
+	if err := store.Claim(ctx, "S1"); !errors.Is(err, paycell.ErrSessionClaimed) {
+		t.Errorf("second Claim error = %v, want ErrSessionClaimed", err)
+	}
+	if _, err := store.Load(ctx, "S1"); err != nil {
+		t.Errorf("Load of a claimed session error = %v", err)
+	}
+	if err := store.Release(ctx, "S1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Claim(ctx, "S1"); err != nil {
+		t.Errorf("Claim after Release error = %v", err)
+	}
+	if err := store.Save(ctx, session, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Claim(ctx, "S1"); err != nil {
+		t.Errorf("Claim after saving the session again error = %v", err)
+	}
+	if err := store.Delete(ctx, "S1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "S1"); !errors.Is(err, paycell.ErrSessionNotFound) {
+		t.Errorf("Load after Delete error = %v, want ErrSessionNotFound", err)
+	}
+
+	expired := *session
+	expired.SessionId = "S2"
+	if err := store.Save(ctx, &expired, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Claim(ctx, "S2"); !errors.Is(err, paycell.ErrSessionNotFound) {
+		t.Errorf("Claim of an expired session error = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := store.Load(ctx, "S2"); !errors.Is(err, paycell.ErrSessionNotFound) {
+		t.Errorf("Load of an expired session error = %v, want ErrSessionNotFound", err)
+	}
+}
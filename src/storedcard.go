@@ -0,0 +1,156 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrCardNotFound   = errors.New("paycell: stored card not found")
+	ErrCVCRequired    = errors.New("paycell: card security code required")
+	ErrThreeDRequired = errors.New("paycell: stored card requires 3D secure")
+	ErrOTPRequired    = errors.New("paycell: stored card requires OTP validation")
+)
+
+// StoredCardRequest selects a card returned by GetPaymentMethods or GetCards.
+type StoredCardRequest struct {
+	CardId string
+	// CVC is tokenized on its own and sent along with the card id when set.
+	CVC        string
+	RequireCVC bool
+	// OTPValidated confirms that SendOTP and ValidateOTP were completed for
+	// this payment; it is required for cards that are not OTP validated.
+	OTPValidated bool
+}
+
+func (c *Client) AuthWithCard(ctx context.Context, req *StoredCardRequest, p Params) (*ProvisionResult, error) {
+	return c.withCard(ctx, "AuthWithCard", "SALE", req, p)
+}
+
+func (c *Client) PreAuthWithCard(ctx context.Context, req *StoredCardRequest, p Params) (*ProvisionResult, error) {
+	return c.withCard(ctx, "PreAuthWithCard", "PREAUTH", req, p)
+}
+
+// Auth3DinitWithCard starts a 3D session for a stored card; 3D replaces the
+// card's 3D and OTP validation requirements.
+func (c *Client) Auth3DinitWithCard(ctx context.Context, req *StoredCardRequest, p Params) (*ThreeDSessionResult, error) {
+	return c.withCard3D(ctx, "Auth3DinitWithCard", "AUTH", req, p)
+}
+
+func (c *Client) PreAuth3DinitWithCard(ctx context.Context, req *StoredCardRequest, p Params) (*ThreeDSessionResult, error) {
+	return c.withCard3D(ctx, "PreAuth3DinitWithCard", "PREAUTH", req, p)
+}
+
+func (c *Client) withCard(ctx context.Context, operation, paymentType string, req *StoredCardRequest, p Params) (res *ProvisionResult, err error) {
+	ctx, span := c.trace(ctx, operation)
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ProvisionResult)
+	if err := p.Amount.Validate(); err != nil {
+		return res, err
+	}
+	card, token, err := c.storedCard(ctx, req, p)
+	if err != nil {
+		res.Header = new(ResponseHeader)
+		return res, err
+	}
+	if !card.IsThreeDValidated {
+		res.Header = new(ResponseHeader)
+		return res, ErrThreeDRequired
+	}
+	if !card.IsOTPValidated && !req.OTPValidated {
+		res.Header = new(ResponseHeader)
+		return res, ErrOTPRequired
+	}
+	provision := &ProvisionRequest{CardId: req.CardId}
+	if token != "" {
+		provision.CardToken = token
+	}
+	return c.provision(ctx, operation, paymentType, "", provision, p)
+}
+
+func (c *Client) withCard3D(ctx context.Context, operation, transaction string, req *StoredCardRequest, p Params) (res *ThreeDSessionResult, err error) {
+	ctx, span := c.trace(ctx, operation)
+	defer func() { span.end(req, res, err) }()
+	ctx, cancel := c.budget(ctx)
+	defer cancel()
+	res = new(ThreeDSessionResult)
+	if err := p.Amount.Validate(); err != nil {
+		return res, err
+	}
+	_, token, err := c.storedCard(ctx, req, p)
+	if err != nil {
+		res.Header = new(ResponseHeader)
+		return res, err
+	}
+	session := &ThreeDSessionRequest{CardId: req.CardId}
+	if token != "" {
+		session.CardToken = token
+	}
+	return c.threeDSession(ctx, operation, transaction, session, p)
+}
+
+// storedCard looks the card up in the customer's payment methods and
+// tokenizes the CVC when given.
+func (c *Client) storedCard(ctx context.Context, req *StoredCardRequest, p Params) (card *StoredCard, token string, err error) {
+	if req.RequireCVC && req.CVC == "" {
+		return nil, "", ErrCVCRequired
+	}
+	methods, err := c.GetPaymentMethods(ctx, new(PaymentMethodsRequest), p)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, stored := range methods.CardList {
+		if string(stored.CardId) == req.CardId {
+			card = stored
+		}
+	}
+	if card == nil {
+		return nil, "", ErrCardNotFound
+	}
+	if card.IsExpired {
+		return nil, "", &Error{Operation: "StoredCard", Description: "card expired", Category: ErrInvalidCard}
+	}
+	if req.CVC != "" {
+		res, err := c.tokenize(ctx, &CardTokenRequest{CardCode: req.CVC})
+		if err != nil {
+			return nil, "", err
+		}
+		token = res.Token
+	}
+	return card, token, nil
+}
+
+// storedCardRequest builds the stored card selection from the legacy request:
+// the card id of Provision (or ThreeDSession) and the CVC set with SetCardCode.
+func (req *Request) storedCardRequest(cardId any) *StoredCardRequest {
+	r := new(StoredCardRequest)
+	r.CardId, _ = cardId.(string)
+	r.CVC, _ = req.CardToken.CardCode.(string)
+	return r
+}
+
+func (api *API) AuthWithCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().AuthWithCard(ctx, req.storedCardRequest(req.Provision.CardId), api.params())
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) PreAuthWithCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().PreAuthWithCard(ctx, req.storedCardRequest(req.Provision.CardId), api.params())
+	res.Provision = *r
+	return res, err
+}
+
+func (api *API) Auth3DinitWithCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().Auth3DinitWithCard(ctx, req.storedCardRequest(req.ThreeDSession.CardId), api.params())
+	res.ThreeDSession = *r
+	return res, err
+}
+
+func (api *API) PreAuth3DinitWithCard(ctx context.Context, req *Request) (res Response, err error) {
+	r, err := api.client().PreAuth3DinitWithCard(ctx, req.storedCardRequest(req.ThreeDSession.CardId), api.params())
+	res.ThreeDSession = *r
+	return res, err
+}
@@ -0,0 +1,75 @@
+package paycell
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// PendingSession is everything needed to finish a 3D Secure payment once the
+// customer returns from the bank: the session created by Auth3Dinit or
+// PreAuth3Dinit, the tokenized or stored card and the original payment
+// parameters.
+type PendingSession struct {
+	SessionId   string
+	CardId      string
+	CardToken   string
+	RefNo       string
+	Transaction string
+	Params
+}
+
+func (c *Client) Complete3D(ctx context.Context, session *PendingSession) (*ProvisionResult, error) {
+	return c.complete3D(ctx, "Complete3D", "SALE", session)
+}
+
+func (c *Client) CompletePreAuth3D(ctx context.Context, session *PendingSession) (*ProvisionResult, error) {
+	return c.complete3D(ctx, "CompletePreAuth3D", "PREAUTH", session)
+}
+
+func (c *Client) complete3D(ctx context.Context, operation, paymentType string, session *PendingSession) (res *ProvisionResult, err error) {
+	ctx, span := c.trace(ctx, operation)
+	defer func() { span.end(session, res, err) }()
+	if err := session.Params.validate(); err != nil {
+		return new(ProvisionResult), err
+	}
+	if c.sessions != nil {
+		if err := c.sessions.Claim(ctx, session.SessionId); err != nil {
+			return new(ProvisionResult), err
+		}
+	}
+	result, err := c.Auth3D(ctx, &ThreeDResultRequest{ThreeDSession: session.SessionId}, session.Params)
+	if err != nil {
+		return new(ProvisionResult), c.release(ctx, session, err)
+	}
+	if code, err := strconv.Atoi(result.Operation.Result); err != nil || code != 0 {
+		return new(ProvisionResult), c.release(ctx, session, &Error{
+			Operation:     operation,
+			Code:          result.Operation.Result,
+			Description:   result.Operation.Description,
+			TransactionId: result.Header.TransactionId,
+			Category:      ErrThreeDSecure,
+		})
+	}
+	req := &ProvisionRequest{ThreeDSession: session.SessionId}
+	if session.CardId != "" {
+		req.CardId = session.CardId
+	}
+	if session.CardToken != "" {
+		req.CardToken = session.CardToken
+	}
+	return c.provision(ctx, operation, paymentType, session.RefNo, req, session.Params)
+}
+
+// release un-claims a session after a failure that happened before the
+// provision was sent, so that the customer's retry can complete it. It runs
+// detached from ctx, which may be the cause of the failure.
+func (c *Client) release(ctx context.Context, session *PendingSession, cause error) error {
+	if c.sessions == nil {
+		return cause
+	}
+	if err := c.sessions.Release(context.WithoutCancel(ctx), session.SessionId); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
@@ -0,0 +1,111 @@
+package paycell
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Attribute is a span attribute. Values are string, int64 or bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts a span for every Paycell operation. Implementations return
+// a context carrying the span so that nested operations, such as the card
+// tokenization inside Auth, become its children. An OpenTelemetry tracer is
+// adapted by wrapping trace.Tracer.Start and the returned trace.Span, mapping
+// each Attribute to attribute.String, attribute.Int64 or attribute.Bool by the
+// type of its value.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Span attribute keys.
+const (
+	AttrOperation    = "paycell.operation"
+	AttrMode         = "paycell.mode"
+	AttrMerchantCode = "paycell.merchant_code"
+	AttrResponseCode = "paycell.response_code"
+	AttrRefNo        = "paycell.reference_number"
+	AttrLatency      = "paycell.latency_ms"
+)
+
+func WithTracer(tracer Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) RecordError(err error)            {}
+func (noopSpan) End()                             {}
+
+type span struct {
+	Span
+	start time.Time
+}
+
+func (c *Client) trace(ctx context.Context, operation string) (context.Context, *span) {
+	tracer := c.tracer
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	ctx, s := tracer.Start(ctx, "paycell."+operation,
+		Attribute{AttrOperation, operation},
+		Attribute{AttrMode, c.environment()},
+		Attribute{AttrMerchantCode, c.merchant},
+	)
+	return ctx, &span{Span: s, start: time.Now()}
+}
+
+// end records the latency, the response code and the reference number of
+// req (or res when req has none) and ends the span.
+func (s *span) end(req, res any, err error) {
+	attrs := []Attribute{{AttrLatency, time.Since(s.start).Milliseconds()}}
+	if header := responseHeader(res); header != nil && header.ResponseCode != "" {
+		attrs = append(attrs, Attribute{AttrResponseCode, header.ResponseCode})
+	}
+	if refNo := field(req, "RefNo"); refNo != "" {
+		attrs = append(attrs, Attribute{AttrRefNo, refNo})
+	} else if refNo := field(res, "RefNo"); refNo != "" {
+		attrs = append(attrs, Attribute{AttrRefNo, refNo})
+	}
+	s.SetAttributes(attrs...)
+	if err != nil {
+		s.RecordError(err)
+	}
+	s.End()
+}
+
+func field(v any, name string) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := rv.FieldByName(name); f.IsValid() {
+		return String(f)
+	}
+	return ""
+}
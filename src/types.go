@@ -0,0 +1,187 @@
+package paycell
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Location is the time zone of dates returned by Paycell.
+var Location = time.FixedZone("TRT", 3*60*60)
+
+var timeLayouts = []string{
+	"20060102150405.000",
+	"20060102150405",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"20060102",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+// Text is a string that also accepts JSON numbers.
+type Text string
+
+// Int is an integer that also accepts JSON strings.
+type Int int
+
+// Time is a timestamp in any of Paycell's date formats, marshaled as
+// yyyyMMddHHmmssSSS. Unrecognized formats decode to the zero time rather than
+// failing the whole response.
+type Time struct {
+	time.Time
+}
+
+// Date is a calendar date in any of Paycell's date formats, marshaled as yyyyMMdd.
+type Date struct {
+	time.Time
+}
+
+type CardBrand string
+
+const (
+	CardBrandVisa       CardBrand = "VISA"
+	CardBrandMasterCard CardBrand = "MASTERCARD"
+	CardBrandAmex       CardBrand = "AMEX"
+	CardBrandTroy       CardBrand = "TROY"
+)
+
+type CardType string
+
+const (
+	CardTypeCredit  CardType = "CREDIT"
+	CardTypeDebit   CardType = "DEBIT"
+	CardTypePrepaid CardType = "PREPAID"
+)
+
+// scalar returns the raw value of a JSON string or number, or "" for null.
+func scalar(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return strings.TrimSpace(s), err
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", fmt.Errorf("paycell: expected string or number, got %s", data)
+	}
+	return n.String(), nil
+}
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	if err != nil {
+		return err
+	}
+	*t = Text(s)
+	return nil
+}
+
+func (t Text) String() string {
+	return string(t)
+}
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	if err != nil || s == "" {
+		*i = 0
+		return err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("paycell: invalid integer %q", s)
+	}
+	*i = Int(n)
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if len(s) == 17 && numeric(s) {
+		s = s[:14] + "." + s[14:]
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("paycell: invalid date %q", s)
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	if err != nil || s == "" {
+		t.Time = time.Time{}
+		return err
+	}
+	t.Time, _ = parseTime(s)
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(strings.ReplaceAll(t.In(Location).Format("20060102150405.000"), ".", ""))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	if err != nil || s == "" {
+		d.Time = time.Time{}
+		return err
+	}
+	d.Time, _ = parseTime(s)
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(d.In(Location).Format("20060102"))
+}
+
+func (b *CardBrand) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	*b = CardBrand(strings.ToUpper(s))
+	return err
+}
+
+func (t *CardType) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	*t = CardType(strings.ToUpper(s))
+	return err
+}
+
+// UnmarshalJSON reads an amount in minor units; the currency defaults to TRY.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s, err := scalar(data)
+	if err != nil || s == "" {
+		*m = Money{}
+		return err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	currency := m.Currency
+	if currency == "" {
+		currency = "TRY"
+	}
+	*m = Money{Amount: n, Currency: currency}
+	return nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Minor())
+}
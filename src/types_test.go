@@ -0,0 +1,139 @@
+package paycell_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	paycell "github.com/ozgur-yalcin/paycell.go/src"
+)
+
+func TestUnmarshalScalars(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		target  func() any
+		want    any
+		wantErr bool
+	}{
+		{name: "text string", input: `" ORDER1 "`, target: func() any { return new(paycell.Text) }, want: paycell.Text("ORDER1")},
+		{name: "text number", input: `123456`, target: func() any { return new(paycell.Text) }, want: paycell.Text("123456")},
+		{name: "text null", input: `null`, target: func() any { return new(paycell.Text) }, want: paycell.Text("")},
+		{name: "text object", input: `{}`, target: func() any { return new(paycell.Text) }, wantErr: true},
+		{name: "int number", input: `12`, target: func() any { return new(paycell.Int) }, want: paycell.Int(12)},
+		{name: "int string", input: `"12"`, target: func() any { return new(paycell.Int) }, want: paycell.Int(12)},
+		{name: "int null", input: `null`, target: func() any { return new(paycell.Int) }, want: paycell.Int(0)},
+		{name: "int empty", input: `""`, target: func() any { return new(paycell.Int) }, want: paycell.Int(0)},
+		{name: "int fraction", input: `"3.5"`, target: func() any { return new(paycell.Int) }, wantErr: true},
+		{name: "brand", input: `"visa"`, target: func() any { return new(paycell.CardBrand) }, want: paycell.CardBrandVisa},
+		{name: "brand upper", input: `"MasterCard"`, target: func() any { return new(paycell.CardBrand) }, want: paycell.CardBrandMasterCard},
+		{name: "type", input: `" debit "`, target: func() any { return new(paycell.CardType) }, want: paycell.CardTypeDebit},
+		{name: "type null", input: `null`, target: func() any { return new(paycell.CardType) }, want: paycell.CardType("")},
+		{name: "money number", input: `1050`, target: func() any { return new(paycell.Money) }, want: paycell.NewMoney(1050, "TRY")},
+		{name: "money string", input: `"1050"`, target: func() any { return new(paycell.Money) }, want: paycell.NewMoney(1050, "TRY")},
+		{name: "money null", input: `null`, target: func() any { return new(paycell.Money) }, want: paycell.Money{}},
+		{name: "money decimal", input: `"10.50"`, target: func() any { return new(paycell.Money) }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.target()
+			err := json.Unmarshal([]byte(tt.input), v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) succeeded", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.input, err)
+			}
+			var got any
+			switch v := v.(type) {
+			case *paycell.Text:
+				got = *v
+			case *paycell.Int:
+				got = *v
+			case *paycell.CardBrand:
+				got = *v
+			case *paycell.CardType:
+				got = *v
+			case *paycell.Money:
+				got = *v
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTime(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{`"20240102150405123"`, time.Date(2024, 1, 2, 15, 4, 5, 123e6, paycell.Location)},
+		{`20240102150405123`, time.Date(2024, 1, 2, 15, 4, 5, 123e6, paycell.Location)},
+		{`"20240102150405"`, time.Date(2024, 1, 2, 15, 4, 5, 0, paycell.Location)},
+		{`"2024-01-02T15:04:05.123+03:00"`, time.Date(2024, 1, 2, 15, 4, 5, 123e6, paycell.Location)},
+		{`"2024-01-02T12:04:05Z"`, time.Date(2024, 1, 2, 15, 4, 5, 0, paycell.Location)},
+		{`"2024-01-02T15:04:05"`, time.Date(2024, 1, 2, 15, 4, 5, 0, paycell.Location)},
+		{`"2024-01-02 15:04:05"`, time.Date(2024, 1, 2, 15, 4, 5, 0, paycell.Location)},
+		{`"20240102"`, time.Date(2024, 1, 2, 0, 0, 0, 0, paycell.Location)},
+		{`"2024-01-02"`, time.Date(2024, 1, 2, 0, 0, 0, 0, paycell.Location)},
+		{`"02.01.2024"`, time.Date(2024, 1, 2, 0, 0, 0, 0, paycell.Location)},
+		{`null`, time.Time{}},
+		{`""`, time.Time{}},
+		{`"yesterday"`, time.Time{}},
+		{`"2024-13-45"`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var ts paycell.Time
+			if err := json.Unmarshal([]byte(tt.input), &ts); err != nil {
+				t.Fatalf("Time: %v", err)
+			}
+			if !ts.Equal(tt.want) {
+				t.Errorf("Time = %v, want %v", ts.Time, tt.want)
+			}
+			var d paycell.Date
+			if err := json.Unmarshal([]byte(tt.input), &d); err != nil {
+				t.Fatalf("Date: %v", err)
+			}
+			if !d.Equal(tt.want) {
+				t.Errorf("Date = %v, want %v", d.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalTime(t *testing.T) {
+	ts := paycell.Time{Time: time.Date(2024, 1, 2, 12, 4, 5, 123e6, time.UTC)}
+	if data, _ := json.Marshal(ts); string(data) != `"20240102150405123"` {
+		t.Errorf("Time = %s", data)
+	}
+	d := paycell.Date{Time: ts.Time}
+	if data, _ := json.Marshal(d); string(data) != `"20240102"` {
+		t.Errorf("Date = %s", data)
+	}
+	if data, _ := json.Marshal(paycell.Time{}); string(data) != `""` {
+		t.Errorf("zero Time = %s", data)
+	}
+}
+
+func TestUnmarshalLimits(t *testing.T) {
+	var info paycell.MobilePaymentInfo
+	input := `{"limit":"50000","maxLimit":100000,"remainingLimit":"12345","statementDate":"20240115","isDcbOpen":true}`
+	if err := json.Unmarshal([]byte(input), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Limit != paycell.NewMoney(50000, "TRY") || info.MaxLimit != paycell.NewMoney(100000, "TRY") || info.RemainingLimit != paycell.NewMoney(12345, "TRY") {
+		t.Errorf("limits = %v, %v, %v", info.Limit, info.MaxLimit, info.RemainingLimit)
+	}
+	if info.RemainingLimit.Decimal() != "123.45" {
+		t.Errorf("remaining limit = %s, want 123.45", info.RemainingLimit.Decimal())
+	}
+	if !info.StatementDate.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, paycell.Location)) || !info.IsDcbOpen {
+		t.Errorf("info = %+v", info)
+	}
+}